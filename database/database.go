@@ -6,6 +6,7 @@ import (
 	"context"
 	"errors"
 	"flag"
+	"os"
 	"strings"
 	"time"
 
@@ -22,6 +23,7 @@ import (
 
 var log = logrus.New()
 var GitCommit string // Will be set by Bazel at build time
+var BuildDate string // Will be set via -ldflags at build time
 
 // initLogger initializes the logger configuration
 func initLogger() {
@@ -31,12 +33,21 @@ func initLogger() {
 }
 
 var (
-	port = flag.Int("port", 50053, "The server port of the database service")
+	showVersion      = flag.Bool("version", false, "Print version information and exit")
+	port             = flag.Int("port", 50053, "The server port of the database service")
+	enableReflection = flag.Bool("enable-reflection", true, "Register the gRPC reflection service")
+	grpcAuthToken    = flag.String(
+		"grpc-auth-token", "", "Shared secret callers must send as a bearer token (empty disables auth)",
+	)
+	collapseThreads = flag.Bool("collapse-threads", false,
+		"Return only the newest entry of each email thread (same sender and subject) from QueryRecent")
 )
 
 type databaseServer struct {
 	pb.DataBaseServiceServer
 	db *gorm.DB
+	// collapseThreads makes QueryRecent keep only the newest entry of each thread.
+	collapseThreads bool
 }
 
 type DatabaseEntry struct {
@@ -48,6 +59,15 @@ type DatabaseEntry struct {
 	Text        string
 	Summary     string
 	HashId      string `gorm:"index"`
+	// ThreadKey is the sender address plus the normalized email subject, shared by the
+	// entries of one conversation with one correspondent.
+	ThreadKey string `gorm:"index"`
+	// Subject is the original email subject and Sender the lowercased FROM address (without any
+	// display name), both taken from the stored text.
+	// They are write-only for now: pb.DataBaseSchema has no fields to return them, so they only
+	// serve direct SQL queries until the protos carry them.
+	Subject string
+	Sender  string `gorm:"index"`
 }
 
 func (s *databaseServer) CreateIfNotExist(
@@ -81,6 +101,9 @@ func (s *databaseServer) Write(_ context.Context, req *pb.WriteRequest) (*pb.Wri
 		Text:        req.Schema.Text,
 		Summary:     req.Schema.Summary,
 		HashId:      req.Schema.HashId,
+		ThreadKey:   threadKeyFromText(req.Schema.Text),
+		Subject:     textHeader(req.Schema.Text, utils.MailSubjectPrefix),
+		Sender:      senderAddress(textHeader(req.Schema.Text, utils.MailFromPrefix)),
 	}
 	if s.db == nil {
 		return nil, status.Errorf(codes.FailedPrecondition, "database not initialized")
@@ -128,9 +151,14 @@ func (s *databaseServer) QueryRecent(_ context.Context, req *pb.QueryRecentReque
 	from := now.Add(-time.Second * time.Duration(req.TimeAgoInSeconds))
 
 	// Query the database for entries created within the specified time range
-	if err := s.db.Where("created_at BETWEEN ? AND ?", from, now).Find(&entries).Error; err != nil {
+	query := s.db.Where("created_at BETWEEN ? AND ?", from, now).Order("created_at")
+	if err := query.Find(&entries).Error; err != nil {
 		return nil, status.Errorf(codes.Internal, "failed to query database: %v", err)
 	}
+	if s.collapseThreads {
+		// A reply thread is one item for the summary, represented by its newest message
+		entries = newestPerThread(entries)
+	}
 	// Convert entries to protobuf format
 	schemas := make([]*pb.DataBaseSchema, len(entries))
 	for i, entry := range entries {
@@ -193,10 +221,18 @@ func main() {
 	initLogger()
 	flag.Parse()
 
+	if *showVersion {
+		utils.PrintVersion(os.Stdout, "todofy-database", GitCommit, BuildDate)
+		return
+	}
+
 	err := utils.StartGRPCServer[pb.DataBaseServiceServer](
 		*port,
-		&databaseServer{},
+		&databaseServer{collapseThreads: *collapseThreads},
 		pb.RegisterDataBaseServiceServer,
+		utils.SharedSecretServerOption(*grpcAuthToken),
+		utils.WithReflection(*enableReflection),
+		utils.WithRPCLogger(log),
 	)
 	if err != nil {
 		log.Fatalf("server error: %v", err)
@@ -9,6 +9,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	pb "github.com/ziyixi/protos/go/todofy"
+	"github.com/ziyixi/todofy/utils"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -237,6 +238,95 @@ func TestDatabaseServer_QueryRecent(t *testing.T) {
 		assert.Equal(t, "Summary 1", resp.Entries[0].Summary)
 	})
 
+	t.Run("reply threads collapse to the newest message when enabled", func(t *testing.T) {
+		server := setupTestDatabase(t)
+		server.collapseThreads = true
+		ctx := context.Background()
+
+		for _, text := range []string{
+			"SUBJECT: Quarterly review\nFROM: ana@example.com\n\nCONTENT:\nfirst",
+			"SUBJECT: Lunch\nFROM: ana@example.com\n\nCONTENT:\nunrelated",
+			"SUBJECT: Re: Quarterly review\nFROM: Ana <ana@example.com>\n\nCONTENT:\nreply",
+		} {
+			_, err := server.Write(ctx, &pb.WriteRequest{Schema: &pb.DataBaseSchema{Text: text, Summary: text}})
+			require.NoError(t, err)
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		var stored DatabaseEntry
+		require.NoError(t, server.db.Where("text LIKE ?", "%reply").First(&stored).Error)
+		assert.Equal(t, "ana@example.com quarterly review", stored.ThreadKey)
+
+		resp, err := server.QueryRecent(ctx, &pb.QueryRecentRequest{TimeAgoInSeconds: 60})
+		require.NoError(t, err)
+		require.Len(t, resp.Entries, 2)
+		assert.Contains(t, resp.Entries[0].Text, "unrelated")
+		assert.Contains(t, resp.Entries[1].Text, "reply")
+	})
+
+	t.Run("threads are not collapsed by default", func(t *testing.T) {
+		server := setupTestDatabase(t)
+		ctx := context.Background()
+
+		for _, text := range []string{
+			"SUBJECT: Quarterly review\nFROM: ana@example.com\n\nCONTENT:\nfirst",
+			"SUBJECT: Re: Quarterly review\nFROM: ana@example.com\n\nCONTENT:\nreply",
+		} {
+			_, err := server.Write(ctx, &pb.WriteRequest{Schema: &pb.DataBaseSchema{Text: text, Summary: text}})
+			require.NoError(t, err)
+		}
+
+		resp, err := server.QueryRecent(ctx, &pb.QueryRecentRequest{TimeAgoInSeconds: 60})
+		require.NoError(t, err)
+		assert.Len(t, resp.Entries, 2)
+	})
+
+	t.Run("same subject from different senders is not one thread", func(t *testing.T) {
+		server := setupTestDatabase(t)
+		server.collapseThreads = true
+		ctx := context.Background()
+
+		for _, text := range []string{
+			"SUBJECT: Invoice\nFROM: billing@vendor-a.com\n\nCONTENT:\ninvoice a",
+			"SUBJECT: Invoice\nFROM: billing@vendor-b.com\n\nCONTENT:\ninvoice b",
+		} {
+			_, err := server.Write(ctx, &pb.WriteRequest{Schema: &pb.DataBaseSchema{Text: text, Summary: text}})
+			require.NoError(t, err)
+			time.Sleep(10 * time.Millisecond)
+		}
+
+		resp, err := server.QueryRecent(ctx, &pb.QueryRecentRequest{TimeAgoInSeconds: 60})
+		require.NoError(t, err)
+		require.Len(t, resp.Entries, 2)
+		assert.Contains(t, resp.Entries[0].Text, "invoice a")
+		assert.Contains(t, resp.Entries[1].Text, "invoice b")
+	})
+
+	t.Run("subject and sender are stored as columns", func(t *testing.T) {
+		server := setupTestDatabase(t)
+		ctx := context.Background()
+
+		text := utils.MailInputText(utils.MailInfo{
+			Subject: "Lease renewal",
+			From:    "Landlord <Landlord@Example.com>",
+			To:      "me@example.com",
+			Content: "body",
+		})
+		_, err := server.Write(ctx, &pb.WriteRequest{Schema: &pb.DataBaseSchema{Text: text, Summary: "renew"}})
+		require.NoError(t, err)
+
+		var stored DatabaseEntry
+		require.NoError(t, server.db.First(&stored).Error)
+		assert.Equal(t, "Lease renewal", stored.Subject)
+		assert.Equal(t, "landlord@example.com", stored.Sender)
+
+		// The columns aren't part of the RPC response; the stored text is returned unchanged
+		resp, err := server.QueryRecent(ctx, &pb.QueryRecentRequest{TimeAgoInSeconds: 60})
+		require.NoError(t, err)
+		require.Len(t, resp.Entries, 1)
+		assert.Equal(t, text, resp.Entries[0].Text)
+	})
+
 	t.Run("query with zero time range", func(t *testing.T) {
 		server := setupTestDatabase(t)
 
@@ -1,4 +1,7 @@
 #!/bin/sh
 
 /database \
-    -port=${PORT}
\ No newline at end of file
+    -port=${PORT} \
+    -grpc-auth-token=${GRPC_AUTH_TOKEN} \
+    -collapse-threads=${COLLAPSE_THREADS:-false} \
+    -enable-reflection=${ENABLE_GRPC_REFLECTION:-true}
\ No newline at end of file
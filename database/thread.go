@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/mail"
+	"regexp"
+	"strings"
+
+	"github.com/ziyixi/todofy/utils"
+)
+
+// replyPrefixPattern matches any run of reply/forward markers such as "Re:", "FW:", "Fwd:" or "Re[2]:".
+var replyPrefixPattern = regexp.MustCompile(`(?i)^\s*((re|fw|fwd|aw|wg)(\[\d+\])?\s*:\s*)+`)
+
+// normalizeThreadSubject strips reply/forward markers, lowercases and collapses whitespace,
+// so every message in an email conversation maps to the same subject.
+func normalizeThreadSubject(subject string) string {
+	subject = replyPrefixPattern.ReplaceAllString(subject, "")
+	return strings.ToLower(strings.Join(strings.Fields(subject), " "))
+}
+
+// textHeader returns the trimmed value of the header line starting with prefix, or "" when the
+// header block utils.MailInputText puts at the top of the stored text has no such line.
+func textHeader(text, prefix string) string {
+	for line := range strings.Lines(text) {
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if value, ok := strings.CutPrefix(line, prefix); ok {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
+
+// threadKeyFromText derives the thread key from the sender and subject lines of the stored
+// text, so same-subject mails from different senders ("Invoice") stay separate. Text without
+// either line, or with an empty value, has no thread key. The stored text carries no
+// References or In-Reply-To headers to thread on.
+func threadKeyFromText(text string) string {
+	subject := normalizeThreadSubject(textHeader(text, utils.MailSubjectPrefix))
+	sender := senderAddress(textHeader(text, utils.MailFromPrefix))
+	if subject == "" || sender == "" {
+		return ""
+	}
+	return sender + " " + subject
+}
+
+// senderAddress returns the lowercased bare address of a FROM value like "Ana <ana@example.com>".
+func senderAddress(from string) string {
+	if addr, err := mail.ParseAddress(from); err == nil {
+		from = addr.Address
+	}
+	return strings.ToLower(strings.TrimSpace(from))
+}
+
+// newestPerThread keeps only the newest entry of each thread, preserving the order of the rest.
+// Entries are expected oldest-first; entries without a thread key are always kept.
+func newestPerThread(entries []DatabaseEntry) []DatabaseEntry {
+	latest := make(map[string]int, len(entries))
+	for i, entry := range entries {
+		if entry.ThreadKey != "" {
+			latest[entry.ThreadKey] = i
+		}
+	}
+
+	collapsed := make([]DatabaseEntry, 0, len(entries))
+	for i, entry := range entries {
+		if entry.ThreadKey == "" || latest[entry.ThreadKey] == i {
+			collapsed = append(collapsed, entry)
+		}
+	}
+	return collapsed
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/ziyixi/todofy/utils"
+)
+
+func TestNormalizeThreadSubject(t *testing.T) {
+	tests := []struct {
+		subject string
+		want    string
+	}{
+		{"Quarterly review", "quarterly review"},
+		{"Re: Quarterly review", "quarterly review"},
+		{"RE: Fwd: re:  Quarterly   review ", "quarterly review"},
+		{"FW: Quarterly review", "quarterly review"},
+		{"Re[2]: Quarterly review", "quarterly review"},
+		{"Reply needed: Quarterly review", "reply needed: quarterly review"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, normalizeThreadSubject(tt.subject), tt.subject)
+	}
+}
+
+func TestThreadKeyFromText(t *testing.T) {
+	assert.Equal(t, "a@example.com quarterly review",
+		threadKeyFromText("SUBJECT: Re: Quarterly review\nFROM: a@example.com\n\nCONTENT:\nbody"))
+	assert.Equal(t, "ana@example.com quarterly review",
+		threadKeyFromText("SUBJECT: Quarterly review\nFROM: Ana <Ana@Example.com>"))
+	assert.NotEqual(t, threadKeyFromText("SUBJECT: Invoice\nFROM: a@example.com"),
+		threadKeyFromText("SUBJECT: Invoice\nFROM: b@example.com"))
+	assert.Equal(t, "ana@example.com quarterly review", threadKeyFromText(utils.MailInputText(utils.MailInfo{
+		Subject: "Re: Quarterly review",
+		From:    "Ana <ana@example.com>",
+		To:      "me@example.com",
+		Content: "FROM: quoted@example.com",
+	})), "text built by the gateway's formatter")
+	assert.Empty(t, threadKeyFromText("SUBJECT: Quarterly review"))
+	assert.Empty(t, threadKeyFromText("SUBJECT: \nFROM: a@example.com"))
+	assert.Empty(t, threadKeyFromText("plain text without a subject line"))
+}
+
+func TestTextHeader(t *testing.T) {
+	text := "SUBJECT: Re: Quarterly review\nFROM: Ana <ana@example.com>\nTO: me@example.com\n\nCONTENT:\nFROM: quoted"
+	assert.Equal(t, "Re: Quarterly review", textHeader(text, utils.MailSubjectPrefix))
+	assert.Equal(t, "Ana <ana@example.com>", textHeader(text, utils.MailFromPrefix))
+	assert.Empty(t, textHeader("SUBJECT: only\n\nFROM: in the body", utils.MailFromPrefix))
+	assert.Empty(t, textHeader("plain text", utils.MailSubjectPrefix))
+}
+
+func TestNewestPerThread(t *testing.T) {
+	entries := []DatabaseEntry{
+		{Summary: "first", ThreadKey: "review"},
+		{Summary: "unthreaded"},
+		{Summary: "other", ThreadKey: "invoice"},
+		{Summary: "reply", ThreadKey: "review"},
+		{Summary: "also unthreaded"},
+	}
+
+	var summaries []string
+	for _, entry := range newestPerThread(entries) {
+		summaries = append(summaries, entry.Summary)
+	}
+	assert.Equal(t, []string{"unthreaded", "other", "reply", "also unthreaded"}, summaries)
+}
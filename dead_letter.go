@@ -0,0 +1,121 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// deadLetterDir receives the raw payloads of failed update_todo requests; set from --dead-letter-dir.
+// Empty disables dead-lettering.
+var deadLetterDir string
+
+// deadLetterRecord is the JSON stored per failed request, enough to re-POST the body later.
+type deadLetterRecord struct {
+	ReceivedAt string `json:"received_at"`
+	Status     int    `json:"status"`
+	Response   gin.H  `json:"response"`
+	Body       string `json:"body"`
+}
+
+// deadLetterIDPattern matches the record names writeDeadLetter creates, without the .json extension.
+var deadLetterIDPattern = regexp.MustCompile(`^\d{8}T\d{6}\.\d{9}Z-[0-9a-f]{12}$`)
+
+// failUpdateTodo writes the failure response of a summarize, todo or database step, first
+// dead-lettering body when a replay could succeed (429 or 5xx). Requests rejected as invalid
+// would fail the same way again, so they are not kept.
+func failUpdateTodo(c *gin.Context, body []byte, status int, response gin.H) {
+	if status == http.StatusTooManyRequests || status >= http.StatusInternalServerError {
+		if err := writeDeadLetter(deadLetterDir, time.Now(), body, status, response); err != nil {
+			log.Errorf("Failed to write dead-letter record: %v", err)
+		}
+	}
+	c.JSON(status, response)
+}
+
+// writeDeadLetter stores one record in dir, named by time and body hash so retries of
+// the same email sort together without overwriting each other.
+func writeDeadLetter(dir string, now time.Time, body []byte, status int, response gin.H) error {
+	if dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create dead-letter dir: %w", err)
+	}
+
+	record, err := json.MarshalIndent(deadLetterRecord{
+		ReceivedAt: now.UTC().Format(time.RFC3339Nano),
+		Status:     status,
+		Response:   response,
+		Body:       string(body),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode dead-letter record: %w", err)
+	}
+
+	sum := sha256.Sum256(body)
+	name := fmt.Sprintf("%s-%x.json", now.UTC().Format("20060102T150405.000000000Z"), sum[:6])
+	if err := os.WriteFile(filepath.Join(dir, name), record, 0o600); err != nil {
+		return fmt.Errorf("failed to write dead-letter record: %w", err)
+	}
+	return nil
+}
+
+// readDeadLetter loads the record called id (its file name without .json) from dir.
+func readDeadLetter(dir, id string) (deadLetterRecord, error) {
+	var record deadLetterRecord
+	raw, err := os.ReadFile(filepath.Join(dir, id+".json"))
+	if err != nil {
+		return record, err
+	}
+	if err := json.Unmarshal(raw, &record); err != nil {
+		return record, fmt.Errorf("failed to decode dead-letter record: %w", err)
+	}
+	return record, nil
+}
+
+// HandleReprocess re-runs the HandleUpdateTodo pipeline on the raw body of the dead-letter
+// record :id, e.g. once the prompt or a backend is fixed. Query parameters such as ?keep_urls
+// are passed through. The record is removed when the replay succeeds; a replay that fails
+// again is dead-lettered as a new record.
+func HandleReprocess(c *gin.Context) {
+	if deadLetterDir == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "reprocessing needs --dead-letter-dir to be set"})
+		return
+	}
+	id := c.Param("id")
+	if !deadLetterIDPattern.MatchString(id) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid dead-letter id", "id": id})
+		return
+	}
+	record, err := readDeadLetter(deadLetterDir, id)
+	if errors.Is(err, os.ErrNotExist) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "dead-letter record not found", "id": id})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	log.Infof("Reprocessing dead-letter record %s (originally failed with status %d)", id, record.Status)
+	c.Request.Body = io.NopCloser(strings.NewReader(record.Body))
+	c.Request.ContentLength = int64(len(record.Body))
+	HandleUpdateTodo(c)
+
+	if c.Writer.Status() == http.StatusOK {
+		if err := os.Remove(filepath.Join(deadLetterDir, id+".json")); err != nil {
+			log.Warningf("Failed to remove reprocessed dead-letter record %s: %v", id, err)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ziyixi/todofy/testutils/mocks"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	pb "github.com/ziyixi/protos/go/todofy"
+)
+
+func useDeadLetterDir(t *testing.T, dir string) {
+	t.Helper()
+	original := deadLetterDir
+	deadLetterDir = dir
+	t.Cleanup(func() {
+		deadLetterDir = original
+	})
+}
+
+func readDeadLetters(t *testing.T, dir string) []deadLetterRecord {
+	t.Helper()
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	records := make([]deadLetterRecord, 0, len(paths))
+	for _, path := range paths {
+		raw, err := os.ReadFile(path)
+		require.NoError(t, err)
+		var record deadLetterRecord
+		require.NoError(t, json.Unmarshal(raw, &record))
+		records = append(records, record)
+	}
+	return records
+}
+
+func TestHandleUpdateTodo_DeadLettersFailure(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "dead-letter")
+	useDeadLetterDir(t, dir)
+
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockLLM := new(mocks.MockLLMSummaryServiceClient)
+	mockDB.On("CheckExist", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.CheckExistResponse{Entry: nil}, nil)
+	mockLLM.On("Summarize", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, errors.New("llm quota exceeded"))
+
+	w, router := setupUpdateTodoTest(mockDB, mockLLM, nil)
+	body := validEmailJSON("sender@example.com", "me@test.com", "Test Subject", "Test content")
+	req, _ := http.NewRequest(http.MethodPost, "/api/updatetodo", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	records := readDeadLetters(t, dir)
+	require.Len(t, records, 1)
+	assert.Equal(t, http.StatusInternalServerError, records[0].Status)
+	assert.Contains(t, records[0].Response["error in summarizing email"], "llm quota exceeded")
+	assert.Equal(t, body, records[0].Body)
+	_, err := time.Parse(time.RFC3339Nano, records[0].ReceivedAt)
+	assert.NoError(t, err)
+}
+
+func TestHandleUpdateTodo_DeadLettersRateLimitedSummary(t *testing.T) {
+	dir := t.TempDir()
+	useDeadLetterDir(t, dir)
+
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockLLM := new(mocks.MockLLMSummaryServiceClient)
+	mockDB.On("CheckExist", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.CheckExistResponse{Entry: nil}, nil)
+	mockLLM.On("Summarize", mock.Anything, mock.Anything, mock.Anything).
+		Return(nil, status.Error(codes.ResourceExhausted, "daily token limit reached"))
+
+	w, router := setupUpdateTodoTest(mockDB, mockLLM, nil)
+	body := validEmailJSON("sender@example.com", "me@test.com", "Test Subject", "Test content")
+	req, _ := http.NewRequest(http.MethodPost, "/api/updatetodo", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	records := readDeadLetters(t, dir)
+	require.Len(t, records, 1)
+	assert.Equal(t, http.StatusTooManyRequests, records[0].Status)
+}
+
+func TestHandleUpdateTodo_RejectedRequestsWriteNoDeadLetter(t *testing.T) {
+	dir := t.TempDir()
+	useDeadLetterDir(t, dir)
+	useAllowedSenderDomains(t, "example.com")
+
+	t.Run("disallowed sender", func(t *testing.T) {
+		w, router := setupUpdateTodoTest(nil, nil, nil)
+		body := validEmailJSON("someone@other.org", "me@test.com", "Test Subject", "Test content")
+		req, _ := http.NewRequest(http.MethodPost, "/api/updatetodo", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Empty(t, readDeadLetters(t, dir))
+	})
+
+	t.Run("invalid summarize argument", func(t *testing.T) {
+		mockDB := new(mocks.MockDataBaseServiceClient)
+		mockLLM := new(mocks.MockLLMSummaryServiceClient)
+		mockDB.On("CheckExist", mock.Anything, mock.Anything, mock.Anything).
+			Return(&pb.CheckExistResponse{Entry: nil}, nil)
+		mockLLM.On("Summarize", mock.Anything, mock.Anything, mock.Anything).
+			Return(nil, status.Error(codes.InvalidArgument, "input too long"))
+
+		w, router := setupUpdateTodoTest(mockDB, mockLLM, nil)
+		body := validEmailJSON("sender@example.com", "me@test.com", "Test Subject", "Test content")
+		req, _ := http.NewRequest(http.MethodPost, "/api/updatetodo", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Empty(t, readDeadLetters(t, dir))
+	})
+}
+
+func TestHandleUpdateTodo_SuccessWritesNoDeadLetter(t *testing.T) {
+	dir := t.TempDir()
+	useDeadLetterDir(t, dir)
+
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockLLM := new(mocks.MockLLMSummaryServiceClient)
+	mockTodo := new(mocks.MockTodoServiceClient)
+	mockDB.On("CheckExist", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.CheckExistResponse{Entry: nil}, nil)
+	mockLLM.On("Summarize", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.LLMSummaryResponse{Summary: "summary", Model: pb.Model_MODEL_GEMINI_2_5_FLASH}, nil)
+	mockTodo.On("PopulateTodo", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.TodoResponse{}, nil)
+	mockDB.On("Write", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.WriteResponse{}, nil)
+
+	w, router := setupUpdateTodoTest(mockDB, mockLLM, mockTodo)
+	body := validEmailJSON("sender@example.com", "me@test.com", "Test Subject", "Test content")
+	req, _ := http.NewRequest(http.MethodPost, "/api/updatetodo", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, readDeadLetters(t, dir))
+}
+
+func TestWriteDeadLetter_EmptyDirIsNoop(t *testing.T) {
+	assert.NoError(t, writeDeadLetter("", time.Now(), []byte("{}"), http.StatusBadRequest, gin.H{"error": "x"}))
+}
+
+func TestWriteDeadLetter_UniquePerBody(t *testing.T) {
+	dir := t.TempDir()
+	now := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.NoError(t, writeDeadLetter(dir, now, []byte(`{"a":1}`), http.StatusBadRequest, gin.H{"error": "x"}))
+	require.NoError(t, writeDeadLetter(dir, now, []byte(`{"a":2}`), http.StatusBadRequest, gin.H{"error": "x"}))
+	assert.Len(t, readDeadLetters(t, dir), 2)
+}
+
+func TestHandleReprocess_RejectsWithoutRecord(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/reprocess/:id", HandleReprocess)
+	serve := func(id string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/reprocess/"+id, nil))
+		return w.Code
+	}
+	const id = "20240305T093000.000000000Z-0123456789ab"
+
+	useDeadLetterDir(t, "")
+	assert.Equal(t, http.StatusBadRequest, serve(id))
+
+	useDeadLetterDir(t, t.TempDir())
+	assert.Equal(t, http.StatusNotFound, serve(id))
+	assert.Equal(t, http.StatusBadRequest, serve("latest"))
+}
@@ -4,7 +4,33 @@
     -port=${PORT} \
     -allowed-users=${ALLOWED_USERS} \
     -database-path=${DATABASE_PATH} \
+    -database-type=${DATABASE_TYPE:-sqlite} \
     -llm-addr=${LLMAddr} \
     -todo-addr=${TodoAddr} \
     -dependency-addr=${DependencyAddr} \
-    -database-addr=${DatabaseAddr}
+    -database-addr=${DatabaseAddr} \
+    -grpc-auth-token=${GRPC_AUTH_TOKEN} \
+    -health-check-failfast=${HEALTH_CHECK_FAILFAST:-false} \
+    -connect-timeout=${CONNECT_TIMEOUT:-0} \
+    -http-read-timeout=${HTTP_READ_TIMEOUT:-30} \
+    -http-write-timeout=${HTTP_WRITE_TIMEOUT:-300} \
+    -http-idle-timeout=${HTTP_IDLE_TIMEOUT:-120} \
+    -system-email-sender=${SYSTEM_EMAIL_SENDER} \
+    -allowed-sender-domains=${ALLOWED_SENDER_DOMAINS} \
+    -priority-senders=${PRIORITY_SENDERS} \
+    -recommendation-webhook=${RECOMMENDATION_WEBHOOK} \
+    -recommendation-max-entries=${RECOMMENDATION_MAX_ENTRIES:-0} \
+    -entry-limit-max-age-hours=${ENTRY_LIMIT_MAX_AGE_HOURS:-720} \
+    -summary-cron=${SUMMARY_CRON} \
+    -summary-webhook=${SUMMARY_WEBHOOK} \
+    "-summary-categories=${SUMMARY_CATEGORIES}" \
+    "-summary-entry-header=${SUMMARY_ENTRY_HEADER}" \
+    -llm-entries-json=${LLM_ENTRIES_JSON:-false} \
+    "-prompt-prefix=${PROMPT_PREFIX}" \
+    "-prompt-suffix=${PROMPT_SUFFIX}" \
+    -strip-email-images=${STRIP_EMAIL_IMAGES:-false} \
+    -flatten-email-tables=${FLATTEN_EMAIL_TABLES:-false} \
+    -annotate-task-model=${ANNOTATE_TASK_MODEL:-false} \
+    -dead-letter-dir=${DEAD_LETTER_DIR} \
+    -gin-mode=${GIN_MODE:-release} \
+    -api-rate-limit-per-minute=${API_RATE_LIMIT_PER_MINUTE:-0}
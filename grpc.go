@@ -4,15 +4,24 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand/v2"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ziyixi/todofy/utils"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+	"google.golang.org/grpc/status"
 
 	pb "github.com/ziyixi/protos/go/todofy"
 )
@@ -23,11 +32,26 @@ type ClientProvider interface {
 	GetClient(name string) any
 }
 
-// ServiceConfig holds the configuration for a single gRPC service
+// ServiceConfig holds the configuration for a single gRPC service.
+// addr may be a comma-separated list of backends, which are load balanced round-robin.
 type ServiceConfig struct {
 	name      string
 	addr      string
+	authToken string
 	newClient func(*grpc.ClientConn) any
+	// dialOptions are appended to the default dial options, so they can replace the insecure
+	// transport credentials (e.g. with TLS) or dial in-process listeners in tests.
+	dialOptions []grpc.DialOption
+	// connectTimeout makes NewGRPCClients wait up to this long for every connection of the
+	// service to be established, failing startup early (0 = connect lazily on first use).
+	connectTimeout time.Duration
+	// healthTimeout bounds how long WaitForHealthy waits for this service (0 = caller's deadline only).
+	healthTimeout time.Duration
+	// healthInterval is the pause between health probes (0 = defaultHealthCheckInterval).
+	healthInterval time.Duration
+	// healthFailFast makes WaitForHealthy give up as soon as this service answers a probe with
+	// an error that retrying cannot fix, instead of probing until its timeout.
+	healthFailFast bool
 }
 
 // GRPCClients manages multiple gRPC client connections
@@ -37,19 +61,62 @@ type GRPCClients struct {
 }
 
 type serviceState struct {
-	conn   *grpc.ClientConn
-	client any
+	conn           *grpc.ClientConn
+	client         any
+	healthTimeout  time.Duration
+	healthInterval time.Duration
+	healthFailFast bool
+	// backends holds one dedicated connection per address when a service has
+	// several replicas, so health checks can probe each of them.
+	backends []backendConn
 }
 
+type backendConn struct {
+	addr string
+	conn *grpc.ClientConn
+}
+
+// roundRobinServiceConfig spreads calls across every resolved backend, which also
+// covers DNS names that resolve to several replicas.
+const roundRobinServiceConfig = `{"loadBalancingConfig": [{"round_robin":{}}]}`
+
+// defaultHealthCheckInterval is the pause between health probes when a service sets none.
+const defaultHealthCheckInterval = 500 * time.Millisecond
+
+// healthCheckJitterDivisor sets the random jitter added to each probe delay to at most
+// interval/healthCheckJitterDivisor, so probes of many services and backends don't run in lockstep.
+const healthCheckJitterDivisor = 5
+
 var grpcNewClient = grpc.NewClient
 
+// grpcClientsKey is the gin context key holding the request's ClientProvider.
+type grpcClientsKey struct{}
+
+// errClientsNotSet is returned by GetClients when no middleware stored the clients.
+var errClientsNotSet = errors.New("gRPC clients are not set on the request context")
+
 func grpcMiddleware(clients *GRPCClients) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		c.Set(utils.KeyGRPCClients, clients)
+		SetClients(c, clients)
 		c.Next()
 	}
 }
 
+// SetClients stores the clients handlers fetch with GetClients.
+func SetClients(c *gin.Context, clients ClientProvider) {
+	c.Set(grpcClientsKey{}, clients)
+}
+
+// GetClients returns the clients stored by SetClients, or errClientsNotSet if there are none.
+func GetClients(c *gin.Context) (ClientProvider, error) {
+	value, _ := c.Get(grpcClientsKey{})
+	clients, ok := value.(ClientProvider)
+	if !ok || clients == nil {
+		return nil, errClientsNotSet
+	}
+	return clients, nil
+}
+
 // NewGRPCClients creates a new GRPCClients instance with the specified services
 func NewGRPCClients(configs []ServiceConfig) (*GRPCClients, error) {
 	clients := &GRPCClients{
@@ -57,19 +124,128 @@ func NewGRPCClients(configs []ServiceConfig) (*GRPCClients, error) {
 	}
 
 	for _, config := range configs {
-		conn, err := grpcNewClient(config.addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+		state, err := newServiceState(config)
+		if err == nil && config.connectTimeout > 0 {
+			if err = state.waitForConnected(config.connectTimeout); err != nil {
+				state.close()
+			}
+		}
 		if err != nil {
 			clients.Close() // Clean up any connections already established
 			return nil, fmt.Errorf("failed to connect to %s server: %w", config.name, err)
 		}
+		state.healthTimeout = config.healthTimeout
+		state.healthInterval = config.healthInterval
+		state.healthFailFast = config.healthFailFast
+		clients.services[config.name] = state
+	}
+
+	return clients, nil
+}
+
+func newServiceState(config ServiceConfig) (*serviceState, error) {
+	opts := []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	if config.authToken != "" {
+		opts = append(opts, grpc.WithChainUnaryInterceptor(utils.SharedSecretUnaryClientInterceptor(config.authToken)))
+	}
+	opts = append(opts, config.dialOptions...)
+
+	addrs := splitAddrs(config.addr)
+	if len(addrs) <= 1 {
+		conn, err := grpcNewClient(config.addr, append(opts, grpc.WithDefaultServiceConfig(roundRobinServiceConfig))...)
+		if err != nil {
+			return nil, err
+		}
+		return &serviceState{conn: conn, client: config.newClient(conn)}, nil
+	}
 
-		clients.services[config.name] = &serviceState{
-			conn:   conn,
-			client: config.newClient(conn),
+	state := &serviceState{}
+	for _, addr := range addrs {
+		conn, err := grpcNewClient(addr, opts...)
+		if err != nil {
+			state.close()
+			return nil, err
 		}
+		state.backends = append(state.backends, backendConn{addr: addr, conn: conn})
 	}
 
-	return clients, nil
+	// Feed every address to a per-connection resolver so the balancer sees all replicas
+	r := manual.NewBuilderWithScheme("todofy-" + config.name)
+	resolverAddrs := make([]resolver.Address, 0, len(addrs))
+	for _, addr := range addrs {
+		resolverAddrs = append(resolverAddrs, resolver.Address{Addr: addr})
+	}
+	r.InitialState(resolver.State{Addresses: resolverAddrs})
+
+	conn, err := grpcNewClient(
+		r.Scheme()+":///"+config.name,
+		append(opts, grpc.WithResolvers(r), grpc.WithDefaultServiceConfig(roundRobinServiceConfig))...,
+	)
+	if err != nil {
+		state.close()
+		return nil, err
+	}
+	state.conn = conn
+	state.client = config.newClient(conn)
+	return state, nil
+}
+
+// splitAddrs parses a comma-separated address list, dropping empty entries.
+func splitAddrs(addr string) []string {
+	var addrs []string
+	for _, part := range strings.Split(addr, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			addrs = append(addrs, part)
+		}
+	}
+	return addrs
+}
+
+func (s *serviceState) close() {
+	conns := []*grpc.ClientConn{s.conn}
+	for _, backend := range s.backends {
+		conns = append(conns, backend.conn)
+	}
+	for _, conn := range conns {
+		if conn != nil {
+			if err := conn.Close(); err != nil {
+				log.Warningf("Failed to close gRPC connection: %v", err)
+			}
+		}
+	}
+}
+
+// waitForConnected establishes every connection of the service, failing with the first
+// address that is not ready within timeout.
+func (s *serviceState) waitForConnected(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	conns := []backendConn{{addr: s.conn.Target(), conn: s.conn}}
+	if len(s.backends) > 0 {
+		conns = s.backends
+	}
+	for _, backend := range conns {
+		backend.conn.Connect()
+		for state := backend.conn.GetState(); state != connectivity.Ready; state = backend.conn.GetState() {
+			if !backend.conn.WaitForStateChange(ctx, state) {
+				return fmt.Errorf("%s not connected within %s (last state %s)", backend.addr, timeout, state)
+			}
+		}
+	}
+	return nil
+}
+
+// healthTargets returns the connections WaitForHealthy should probe, keyed by label.
+func (s *serviceState) healthTargets(name string) map[string]*grpc.ClientConn {
+	if len(s.backends) == 0 {
+		return map[string]*grpc.ClientConn{name: s.conn}
+	}
+	targets := make(map[string]*grpc.ClientConn, len(s.backends))
+	for _, backend := range s.backends {
+		targets[fmt.Sprintf("%s (%s)", name, backend.addr)] = backend.conn
+	}
+	return targets
 }
 
 // GetClient returns the client for the specified service
@@ -89,11 +265,7 @@ func (c *GRPCClients) Close() {
 	defer c.mu.Unlock()
 
 	for _, service := range c.services {
-		if service.conn != nil {
-			if err := service.conn.Close(); err != nil {
-				log.Warningf("Failed to close gRPC connection: %v", err)
-			}
-		}
+		service.close()
 	}
 }
 
@@ -109,69 +281,249 @@ func (c *GRPCClients) ServiceNames() []string {
 	return names
 }
 
-// WaitForHealthy waits for all services to become healthy
+// backendHealthServices names the per-backend health status each service publishes next to
+// its overall one: "" only says the process is up, these say whether Gemini and Todoist answer.
+var backendHealthServices = map[string]string{
+	"llm":  "gemini",
+	"todo": "todoist",
+}
+
+// healthTarget is one connection probed by WaitForHealthy.
+type healthTarget struct {
+	conn     *grpc.ClientConn
+	timeout  time.Duration
+	interval time.Duration
+	failFast bool
+	// backendService is the per-backend health service CheckHealth also probes, if any.
+	backendService string
+}
+
+// errHealthCheckTerminal marks probe failures that won't recover by waiting: no health service,
+// a rejected auth token, or a service name the server doesn't know.
+var errHealthCheckTerminal = errors.New("health check failed permanently")
+
+// terminalHealthError returns an errHealthCheckTerminal error when a probe's outcome cannot
+// improve on retry, and nil for transient outcomes such as UNAVAILABLE or NOT_SERVING.
+func terminalHealthError(resp *grpc_health_v1.HealthCheckResponse, err error) error {
+	if err != nil {
+		switch status.Code(err) {
+		case codes.Unimplemented, codes.Unauthenticated, codes.PermissionDenied:
+			return fmt.Errorf("%w: %v", errHealthCheckTerminal, err)
+		}
+		return nil
+	}
+	if resp.GetStatus() == grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN {
+		return fmt.Errorf("%w: status %s", errHealthCheckTerminal, resp.GetStatus())
+	}
+	return nil
+}
+
+type healthResult struct {
+	name string
+	err  error
+}
+
+// WaitForHealthy waits for all services to become healthy. Each service is bounded
+// by its own health timeout (if set) as well as ctx, so a slow backend only fails
+// itself; the error lists the services that did become healthy in time. A fail-fast
+// service with a terminal probe error ends the wait right away.
 func (c *GRPCClients) WaitForHealthy(ctx context.Context) error {
-	c.mu.RLock()
-	serviceCount := len(c.services)
-	c.mu.RUnlock()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
 
-	errChan := make(chan error, serviceCount)
+	targets := c.allHealthTargets()
+	resultChan := make(chan healthResult, len(targets))
 	var wg sync.WaitGroup
 
-	c.mu.RLock()
-	for name, service := range c.services {
+	for name, target := range targets {
 		wg.Add(1)
-		go func(name string, conn *grpc.ClientConn) {
+		go func(name string, target healthTarget) {
 			defer wg.Done()
-
-			healthClient := grpc_health_v1.NewHealthClient(conn)
-			ticker := time.NewTicker(500 * time.Millisecond)
-			defer ticker.Stop()
-
-			for {
-				select {
-				case <-ctx.Done():
-					errChan <- fmt.Errorf("health check timeout for %s", name)
-					return
-				case <-ticker.C:
-					req := &grpc_health_v1.HealthCheckRequest{}
-					resp, err := healthClient.Check(ctx, req)
-
-					if err != nil {
-						log.Warningf("Health check error for %s: %v", name, err)
-						continue
-					}
-
-					if resp.Status == grpc_health_v1.HealthCheckResponse_SERVING {
-						errChan <- nil
-						return
-					}
-				}
-			}
-		}(name, service.conn)
+			resultChan <- healthResult{name: name, err: waitForTarget(ctx, name, target)}
+		}(name, target)
 	}
-	c.mu.RUnlock()
 
 	go func() {
 		wg.Wait()
-		close(errChan)
+		close(resultChan)
 	}()
 
 	var healthErrs []error
-	for err := range errChan {
-		if err != nil {
-			healthErrs = append(healthErrs, err)
+	var healthy []string
+	for result := range resultChan {
+		if errors.Is(result.err, errHealthCheckTerminal) {
+			// The remaining probes stop with the canceled context; their results aren't needed
+			sort.Strings(healthy)
+			return fmt.Errorf("health check failed: %w (healthy so far: %v)", result.err, healthy)
+		}
+		if result.err != nil {
+			healthErrs = append(healthErrs, result.err)
+			continue
 		}
+		healthy = append(healthy, result.name)
 	}
 
 	if len(healthErrs) > 0 {
-		return fmt.Errorf("health check failed: %v", healthErrs)
+		sort.Strings(healthy)
+		return fmt.Errorf("health check failed: %v (healthy in time: %v)", healthErrs, healthy)
+	}
+
+	return nil
+}
+
+// allHealthTargets returns every connection to probe, keyed by its service (and replica) label.
+func (c *GRPCClients) allHealthTargets() map[string]healthTarget {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	targets := make(map[string]healthTarget)
+	for name, service := range c.services {
+		interval := service.healthInterval
+		if interval <= 0 {
+			interval = defaultHealthCheckInterval
+		}
+		for label, conn := range service.healthTargets(name) {
+			targets[label] = healthTarget{
+				conn: conn, timeout: service.healthTimeout, interval: interval, failFast: service.healthFailFast,
+				backendService: backendHealthServices[name],
+			}
+		}
+	}
+	return targets
+}
+
+// CheckHealth sends one health check to every connection at once and returns an error naming
+// those that aren't SERVING. Unlike WaitForHealthy it never waits for a probe interval or
+// retries, so a readiness probe answers as fast as the backends do. The llm and todo services
+// must also report their backend (backendHealthServices) SERVING, so an unreachable Gemini
+// or a rejected Todoist key makes the gateway not ready.
+func (c *GRPCClients) CheckHealth(ctx context.Context) error {
+	targets := c.allHealthTargets()
+	resultChan := make(chan healthResult, len(targets))
+	for name, target := range targets {
+		go func(name string, target healthTarget) {
+			resultChan <- healthResult{name: name, err: checkTarget(ctx, target)}
+		}(name, target)
 	}
 
+	var unhealthy []string
+	for range targets {
+		if result := <-resultChan; result.err != nil {
+			unhealthy = append(unhealthy, fmt.Sprintf("%s: %v", result.name, result.err))
+		}
+	}
+	if len(unhealthy) > 0 {
+		sort.Strings(unhealthy)
+		return fmt.Errorf("health check failed: %s", strings.Join(unhealthy, "; "))
+	}
 	return nil
 }
 
-func (c *GRPCClients) SetUpDataBase(path string) error {
+// checkTarget probes the overall and, if set, backend health status of target once, failing
+// unless both report SERVING.
+func checkTarget(ctx context.Context, target healthTarget) error {
+	healthClient := grpc_health_v1.NewHealthClient(target.conn)
+	resp, err := healthClient.Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	if err != nil {
+		return err
+	}
+	if resp.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		return fmt.Errorf("status %s", resp.Status)
+	}
+	if target.backendService == "" {
+		return nil
+	}
+	resp, err = healthClient.Check(ctx, &grpc_health_v1.HealthCheckRequest{Service: target.backendService})
+	if err != nil {
+		return fmt.Errorf("%s backend: %w", target.backendService, err)
+	}
+	if resp.Status != grpc_health_v1.HealthCheckResponse_SERVING {
+		return fmt.Errorf("%s backend status %s", target.backendService, resp.Status)
+	}
+	return nil
+}
+
+// healthProbeDelay returns interval plus a random jitter of up to interval/healthCheckJitterDivisor.
+func healthProbeDelay(interval time.Duration) time.Duration {
+	maxJitter := interval / healthCheckJitterDivisor
+	if maxJitter <= 0 {
+		return interval
+	}
+	return interval + rand.N(maxJitter)
+}
+
+// waitForTarget probes one connection until it reports SERVING or its deadline passes.
+func waitForTarget(ctx context.Context, name string, target healthTarget) error {
+	if target.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, target.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	healthClient := grpc_health_v1.NewHealthClient(target.conn)
+	timer := time.NewTimer(healthProbeDelay(target.interval))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("health check timeout for %s", name)
+		case <-timer.C:
+			timer.Reset(healthProbeDelay(target.interval))
+			req := &grpc_health_v1.HealthCheckRequest{}
+			resp, err := healthClient.Check(ctx, req)
+			if target.failFast {
+				if terminalErr := terminalHealthError(resp, err); terminalErr != nil {
+					return fmt.Errorf("%s: %w", name, terminalErr)
+				}
+			}
+
+			if err != nil {
+				log.Warningf("Health check error for %s: %v", name, err)
+				continue
+			}
+
+			if resp.Status == grpc_health_v1.HealthCheckResponse_SERVING {
+				log.Infof("%s is healthy after %s", name, time.Since(start).Round(time.Millisecond))
+				return nil
+			}
+		}
+	}
+}
+
+// databaseSetup controls how SetUpDataBase retries: the database service can report SERVING a
+// moment before it accepts CreateIfNotExist, so startup retries with doubling backoff instead of
+// failing on the first error.
+type databaseSetup struct {
+	maxAttempts  int // from --database-setup-attempts; at least one attempt is made
+	retryBackoff time.Duration
+	timeout      time.Duration // per attempt
+}
+
+const (
+	defaultDatabaseSetupRetryBackoff = time.Second
+	defaultDatabaseSetupTimeout      = 10 * time.Second
+)
+
+// databaseType is sent with every database request so the backend picks the right store.
+var databaseType = pb.DatabaseType_DATABASE_TYPE_SQLITE // set from --database-type
+
+// parseDatabaseType accepts a backend name such as "sqlite" or the full enum name
+// "DATABASE_TYPE_SQLITE". Any type the protos define other than UNSPECIFIED is allowed.
+func parseDatabaseType(raw string) (pb.DatabaseType, error) {
+	normalized := strings.ToUpper(strings.TrimSpace(raw))
+	if !strings.HasPrefix(normalized, "DATABASE_TYPE_") {
+		normalized = "DATABASE_TYPE_" + normalized
+	}
+	value, exists := pb.DatabaseType_value[normalized]
+	if !exists || pb.DatabaseType(value) == pb.DatabaseType_DATABASE_TYPE_UNSPECIFIED {
+		return pb.DatabaseType_DATABASE_TYPE_UNSPECIFIED, fmt.Errorf("unknown database type %q", raw)
+	}
+	return pb.DatabaseType(value), nil
+}
+
+func (c *GRPCClients) SetUpDataBase(path string, setup databaseSetup) error {
 	client := c.GetClient("database")
 	if client == nil {
 		return fmt.Errorf("database client is not configured")
@@ -183,12 +535,24 @@ func (c *GRPCClients) SetUpDataBase(path string) error {
 	}
 
 	req := &pb.CreateIfNotExistRequest{
-		Type: pb.DatabaseType_DATABASE_TYPE_SQLITE,
+		Type: databaseType,
 		Path: path,
 	}
-	_, err := databaseClient.CreateIfNotExist(context.Background(), req)
-	if err != nil {
-		return fmt.Errorf("failed to set up database: %w", err)
+	attempts := max(setup.maxAttempts, 1)
+	backoff := setup.retryBackoff
+	var err error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), setup.timeout)
+		_, err = databaseClient.CreateIfNotExist(ctx, req)
+		cancel()
+		if err == nil {
+			return nil
+		}
+		log.Warningf("Database setup attempt %d/%d failed: %v", attempt, attempts, err)
+		if attempt < attempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
 	}
-	return nil
+	return fmt.Errorf("failed to set up database after %d attempts: %w", attempts, err)
 }
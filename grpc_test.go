@@ -4,12 +4,19 @@ import (
 	"context"
 	"fmt"
 	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"github.com/ziyixi/todofy/testutils"
 	"github.com/ziyixi/todofy/testutils/mocks"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
@@ -51,6 +58,46 @@ func TestNewGRPCClients(t *testing.T) {
 		assert.Equal(t, "client", clients.GetClient("configured-service"))
 	})
 
+	t.Run("adds auth interceptor only when token is configured", func(t *testing.T) {
+		var optionCounts []int
+		grpcNewClient = func(_ string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+			optionCounts = append(optionCounts, len(opts))
+			return &grpc.ClientConn{}, nil
+		}
+		t.Cleanup(func() {
+			grpcNewClient = grpc.NewClient
+		})
+
+		newClient := func(_ *grpc.ClientConn) any { return "client" }
+		_, err := NewGRPCClients([]ServiceConfig{
+			{name: "plain", addr: "ignored", newClient: newClient},
+			{name: "authed", addr: "ignored", authToken: "secret", newClient: newClient},
+		})
+		require.NoError(t, err)
+		// Every connection also carries the round-robin service config.
+		assert.Equal(t, []int{2, 3}, optionCounts)
+	})
+
+	t.Run("appends configured dial options", func(t *testing.T) {
+		var optionCounts []int
+		grpcNewClient = func(_ string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+			optionCounts = append(optionCounts, len(opts))
+			return &grpc.ClientConn{}, nil
+		}
+		t.Cleanup(func() {
+			grpcNewClient = grpc.NewClient
+		})
+
+		_, err := NewGRPCClients([]ServiceConfig{{
+			name:        "llm",
+			addr:        "ignored",
+			newClient:   func(_ *grpc.ClientConn) any { return "client" },
+			dialOptions: []grpc.DialOption{grpc.WithUserAgent("a"), grpc.WithUserAgent("b")},
+		}})
+		require.NoError(t, err)
+		assert.Equal(t, []int{4}, optionCounts)
+	})
+
 	t.Run("returns wrapped error when connection fails", func(t *testing.T) {
 		grpcNewClient = func(string, ...grpc.DialOption) (*grpc.ClientConn, error) {
 			return nil, status.Error(codes.Unavailable, "dial failed")
@@ -176,7 +223,308 @@ func TestGRPCClients_WaitForHealthy(t *testing.T) {
 	})
 }
 
+// newHealthStatusConn serves a health service reporting the given status per service name.
+func newHealthStatusConn(
+	t *testing.T, statuses map[string]grpc_health_v1.HealthCheckResponse_ServingStatus,
+) *grpc.ClientConn {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	healthServer := health.NewServer()
+	for service, status := range statuses {
+		healthServer.SetServingStatus(service, status)
+	}
+	grpc_health_v1.RegisterHealthServer(server, healthServer)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+		server.Stop()
+	})
+	return conn
+}
+
+func TestGRPCClients_CheckHealth(t *testing.T) {
+	t.Run("probes once without waiting for the interval", func(t *testing.T) {
+		conn, cleanup := newBufconnConn(t, true)
+		defer cleanup()
+
+		clients := &GRPCClients{
+			services: map[string]*serviceState{
+				"database": {conn: conn, healthInterval: time.Hour},
+			},
+		}
+
+		start := time.Now()
+		require.NoError(t, clients.CheckHealth(context.Background()))
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("names every service that is not serving", func(t *testing.T) {
+		healthy, cleanup := newBufconnConn(t, true)
+		defer cleanup()
+		noHealthService, cleanupNoHealth := newBufconnConn(t, false)
+		defer cleanupNoHealth()
+
+		clients := &GRPCClients{
+			services: map[string]*serviceState{
+				"database": {conn: healthy},
+				"llm":      {conn: newDelayedHealthyConn(t, time.Hour)},
+				"todo":     {conn: noHealthService},
+			},
+		}
+
+		err := clients.CheckHealth(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "llm: status NOT_SERVING")
+		assert.Contains(t, err.Error(), "todo: rpc error: code = Unimplemented")
+		assert.NotContains(t, err.Error(), "database")
+	})
+
+	t.Run("backend statuses of llm and todo must be serving", func(t *testing.T) {
+		serving := grpc_health_v1.HealthCheckResponse_SERVING
+		clients := &GRPCClients{
+			services: map[string]*serviceState{
+				"llm": {conn: newHealthStatusConn(t, map[string]grpc_health_v1.HealthCheckResponse_ServingStatus{
+					"": serving, "gemini": grpc_health_v1.HealthCheckResponse_NOT_SERVING,
+				})},
+				"todo": {conn: newHealthStatusConn(t, map[string]grpc_health_v1.HealthCheckResponse_ServingStatus{
+					"": serving, "todoist": serving,
+				})},
+				// The database has no backend status to check
+				"database": {conn: newHealthStatusConn(t, map[string]grpc_health_v1.HealthCheckResponse_ServingStatus{
+					"": serving,
+				})},
+			},
+		}
+
+		err := clients.CheckHealth(context.Background())
+		require.Error(t, err)
+		assert.Equal(t, "health check failed: llm: gemini backend status NOT_SERVING", err.Error())
+	})
+}
+
+// newDelayedHealthyConn serves a health service that reports NOT_SERVING until delay has passed.
+func newDelayedHealthyConn(t *testing.T, delay time.Duration) *grpc.ClientConn {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_NOT_SERVING)
+	grpc_health_v1.RegisterHealthServer(server, healthServer)
+	timer := time.AfterFunc(delay, func() {
+		healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+	})
+	go func() {
+		_ = server.Serve(listener)
+	}()
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		timer.Stop()
+		_ = conn.Close()
+		server.Stop()
+	})
+	return conn
+}
+
+func TestGRPCClients_WaitForHealthyPerServiceTimeouts(t *testing.T) {
+	interval := 10 * time.Millisecond
+
+	t.Run("slow service with a longer timeout gets its full budget", func(t *testing.T) {
+		clients := &GRPCClients{
+			services: map[string]*serviceState{
+				"llm": {conn: newDelayedHealthyConn(t, 0), healthTimeout: 100 * time.Millisecond,
+					healthInterval: interval},
+				"database": {conn: newDelayedHealthyConn(t, 300*time.Millisecond), healthTimeout: 2 * time.Second,
+					healthInterval: interval},
+			},
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		require.NoError(t, clients.WaitForHealthy(ctx))
+	})
+
+	t.Run("reports which services became healthy in time", func(t *testing.T) {
+		clients := &GRPCClients{
+			services: map[string]*serviceState{
+				"llm":  {conn: newDelayedHealthyConn(t, 0), healthInterval: interval},
+				"todo": {conn: newDelayedHealthyConn(t, 50*time.Millisecond), healthInterval: interval},
+				"database": {conn: newDelayedHealthyConn(t, time.Hour), healthTimeout: 200 * time.Millisecond,
+					healthInterval: interval},
+			},
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+		defer cancel()
+		start := time.Now()
+		err := clients.WaitForHealthy(ctx)
+		require.Error(t, err)
+		assert.Less(t, time.Since(start), 2*time.Second, "per-service timeout should fire before the overall deadline")
+		assert.Contains(t, err.Error(), "health check timeout for database")
+		assert.Contains(t, err.Error(), "healthy in time: [llm todo]")
+	})
+}
+
+func TestGRPCClients_WaitForHealthyFailFast(t *testing.T) {
+	interval := 10 * time.Millisecond
+
+	t.Run("permanently down service fails fast", func(t *testing.T) {
+		down, cleanup := newBufconnConn(t, false) // no health service: Unimplemented
+		defer cleanup()
+		clients := &GRPCClients{
+			services: map[string]*serviceState{
+				"llm":      {conn: newDelayedHealthyConn(t, 0), healthInterval: interval, healthFailFast: true},
+				"database": {conn: down, healthInterval: interval, healthFailFast: true},
+			},
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		start := time.Now()
+		err := clients.WaitForHealthy(ctx)
+		require.Error(t, err)
+		assert.Less(t, time.Since(start), time.Second, "should not wait for the overall deadline")
+		assert.ErrorIs(t, err, errHealthCheckTerminal)
+		assert.Contains(t, err.Error(), "database")
+	})
+
+	t.Run("not-yet-serving service is retried", func(t *testing.T) {
+		clients := &GRPCClients{
+			services: map[string]*serviceState{
+				"database": {conn: newDelayedHealthyConn(t, 50*time.Millisecond), healthInterval: interval,
+					healthFailFast: true},
+			},
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		require.NoError(t, clients.WaitForHealthy(ctx))
+	})
+
+	t.Run("without failfast a terminal error waits out the timeout", func(t *testing.T) {
+		down, cleanup := newBufconnConn(t, false)
+		defer cleanup()
+		clients := &GRPCClients{
+			services: map[string]*serviceState{"database": {conn: down, healthInterval: interval}},
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		err := clients.WaitForHealthy(ctx)
+		require.Error(t, err)
+		assert.NotErrorIs(t, err, errHealthCheckTerminal)
+		assert.Contains(t, err.Error(), "health check timeout for database")
+	})
+}
+
+func TestTerminalHealthError(t *testing.T) {
+	serving := &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVING}
+	notServing := &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_NOT_SERVING}
+	unknown := &grpc_health_v1.HealthCheckResponse{Status: grpc_health_v1.HealthCheckResponse_SERVICE_UNKNOWN}
+
+	assert.NoError(t, terminalHealthError(serving, nil))
+	assert.NoError(t, terminalHealthError(notServing, nil))
+	assert.NoError(t, terminalHealthError(nil, status.Error(codes.Unavailable, "connection refused")))
+	assert.NoError(t, terminalHealthError(nil, status.Error(codes.DeadlineExceeded, "slow")))
+	assert.ErrorIs(t, terminalHealthError(unknown, nil), errHealthCheckTerminal)
+	for _, code := range []codes.Code{codes.Unimplemented, codes.Unauthenticated, codes.PermissionDenied} {
+		assert.ErrorIs(t, terminalHealthError(nil, status.Error(code, "no")), errHealthCheckTerminal, code.String())
+	}
+}
+
+func TestHealthProbeDelay(t *testing.T) {
+	interval := 500 * time.Millisecond
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 200; i++ {
+		delay := healthProbeDelay(interval)
+		assert.GreaterOrEqual(t, delay, interval)
+		assert.Less(t, delay, interval+interval/healthCheckJitterDivisor)
+		seen[delay] = true
+	}
+	assert.Greater(t, len(seen), 1, "delays should vary")
+	assert.Equal(t, time.Nanosecond, healthProbeDelay(time.Nanosecond), "too small to jitter")
+}
+
+func TestGRPCClients_WaitForHealthySucceedsWithJitter(t *testing.T) {
+	clients := &GRPCClients{services: map[string]*serviceState{}}
+	for _, name := range []string{"llm", "todo", "database"} {
+		clients.services[name] = &serviceState{
+			conn:           newDelayedHealthyConn(t, 30*time.Millisecond),
+			healthInterval: 20 * time.Millisecond,
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancel()
+	require.NoError(t, clients.WaitForHealthy(ctx))
+}
+
+// fastDatabaseSetup retries database setup three times without backoff.
+var fastDatabaseSetup = databaseSetup{maxAttempts: 3, timeout: time.Second}
+
+// useDatabaseType overrides the database type sent with database requests for one test.
+func useDatabaseType(t *testing.T, dbType pb.DatabaseType) {
+	t.Helper()
+	original := databaseType
+	databaseType = dbType
+	t.Cleanup(func() { databaseType = original })
+}
+
+// testDatabaseType stands in for a backend the protos don't name yet, so tests can tell
+// the configured type apart from the SQLite default.
+const testDatabaseType = pb.DatabaseType(2)
+
+func TestParseDatabaseType(t *testing.T) {
+	for _, raw := range []string{"sqlite", " SQLite ", "DATABASE_TYPE_SQLITE"} {
+		dbType, err := parseDatabaseType(raw)
+		require.NoError(t, err, raw)
+		assert.Equal(t, pb.DatabaseType_DATABASE_TYPE_SQLITE, dbType, raw)
+	}
+	for _, raw := range []string{"", "unspecified", "DATABASE_TYPE_UNSPECIFIED", "mongodb"} {
+		_, err := parseDatabaseType(raw)
+		assert.Error(t, err, raw)
+	}
+}
+
 func TestSetUpDataBase(t *testing.T) {
+	t.Run("sends the configured database type", func(t *testing.T) {
+		useDatabaseType(t, testDatabaseType)
+		mockDB := new(mocks.MockDataBaseServiceClient)
+		mockDB.On("CreateIfNotExist", mock.Anything, mock.MatchedBy(func(req *pb.CreateIfNotExistRequest) bool {
+			return req.Type == testDatabaseType && req.Path == "/tmp/test.db"
+		}), mock.Anything).Return(&pb.CreateIfNotExistResponse{}, nil)
+
+		clients := &GRPCClients{
+			services: map[string]*serviceState{
+				"database": {client: mockDB},
+			},
+		}
+
+		require.NoError(t, clients.SetUpDataBase("/tmp/test.db", fastDatabaseSetup))
+		mockDB.AssertExpectations(t)
+	})
+
 	t.Run("success", func(t *testing.T) {
 		mockDB := new(mocks.MockDataBaseServiceClient)
 		mockDB.On("CreateIfNotExist", mock.Anything, mock.Anything, mock.Anything).
@@ -188,7 +536,7 @@ func TestSetUpDataBase(t *testing.T) {
 			},
 		}
 
-		err := clients.SetUpDataBase("/tmp/test.db")
+		err := clients.SetUpDataBase("/tmp/test.db", fastDatabaseSetup)
 		assert.NoError(t, err)
 		mockDB.AssertExpectations(t)
 	})
@@ -204,16 +552,51 @@ func TestSetUpDataBase(t *testing.T) {
 			},
 		}
 
-		err := clients.SetUpDataBase("/tmp/test.db")
+		err := clients.SetUpDataBase("/tmp/test.db", fastDatabaseSetup)
 		assert.Error(t, err)
-		assert.Contains(t, err.Error(), "failed to set up database")
+		assert.Contains(t, err.Error(), "failed to set up database after 3 attempts")
 		assert.Contains(t, err.Error(), "connection refused")
+		mockDB.AssertNumberOfCalls(t, "CreateIfNotExist", 3)
+	})
+
+	t.Run("retries until success", func(t *testing.T) {
+		mockDB := new(mocks.MockDataBaseServiceClient)
+		mockDB.On("CreateIfNotExist", mock.Anything, mock.Anything, mock.Anything).
+			Return(nil, fmt.Errorf("database not ready")).Once()
+		mockDB.On("CreateIfNotExist", mock.Anything, mock.Anything, mock.Anything).
+			Return(&pb.CreateIfNotExistResponse{}, nil).Once()
+
+		clients := &GRPCClients{
+			services: map[string]*serviceState{
+				"database": {client: mockDB},
+			},
+		}
+
+		require.NoError(t, clients.SetUpDataBase("/tmp/test.db", fastDatabaseSetup))
+		mockDB.AssertNumberOfCalls(t, "CreateIfNotExist", 2)
+	})
+
+	t.Run("each attempt has a deadline", func(t *testing.T) {
+		mockDB := new(mocks.MockDataBaseServiceClient)
+		mockDB.On("CreateIfNotExist", mock.MatchedBy(func(ctx context.Context) bool {
+			_, ok := ctx.Deadline()
+			return ok
+		}), mock.Anything, mock.Anything).
+			Return(&pb.CreateIfNotExistResponse{}, nil)
+
+		clients := &GRPCClients{
+			services: map[string]*serviceState{
+				"database": {client: mockDB},
+			},
+		}
+
+		require.NoError(t, clients.SetUpDataBase("/tmp/test.db", fastDatabaseSetup))
 		mockDB.AssertExpectations(t)
 	})
 
 	t.Run("missing database client", func(t *testing.T) {
 		clients := &GRPCClients{services: map[string]*serviceState{}}
-		err := clients.SetUpDataBase("/tmp/test.db")
+		err := clients.SetUpDataBase("/tmp/test.db", fastDatabaseSetup)
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "database client is not configured")
 	})
@@ -225,8 +608,228 @@ func TestSetUpDataBase(t *testing.T) {
 			},
 		}
 
-		err := clients.SetUpDataBase("/tmp/test.db")
+		err := clients.SetUpDataBase("/tmp/test.db", fastDatabaseSetup)
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "database client has unexpected type")
 	})
 }
+
+type countingLLMServer struct {
+	pb.UnimplementedLLMSummaryServiceServer
+	calls atomic.Int32
+}
+
+func (s *countingLLMServer) Summarize(context.Context, *pb.LLMSummaryRequest) (*pb.LLMSummaryResponse, error) {
+	s.calls.Add(1)
+	return &pb.LLMSummaryResponse{}, nil
+}
+
+// useBufconnBackends routes dials for the given addresses to in-memory LLM servers.
+// Backends listed in unhealthy do not register a health service.
+func useBufconnBackends(t *testing.T, addrs []string, unhealthy ...string) map[string]*countingLLMServer {
+	t.Helper()
+
+	listeners := make(map[string]*bufconn.Listener, len(addrs))
+	servers := make(map[string]*countingLLMServer, len(addrs))
+	for _, addr := range addrs {
+		listener := bufconn.Listen(1024 * 1024)
+		server := grpc.NewServer()
+		llmServer := &countingLLMServer{}
+		pb.RegisterLLMSummaryServiceServer(server, llmServer)
+		if !slices.Contains(unhealthy, addr) {
+			healthServer := health.NewServer()
+			healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
+			grpc_health_v1.RegisterHealthServer(server, healthServer)
+		}
+		go func() {
+			_ = server.Serve(listener)
+		}()
+		t.Cleanup(server.Stop)
+		listeners[addr] = listener
+		servers[addr] = llmServer
+	}
+
+	grpcNewClient = func(target string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+		if !strings.Contains(target, ":///") {
+			target = "passthrough:///" + target
+		}
+		opts = append(opts, grpc.WithContextDialer(func(_ context.Context, addr string) (net.Conn, error) {
+			listener, ok := listeners[addr]
+			if !ok {
+				return nil, fmt.Errorf("unknown backend %q", addr)
+			}
+			return listener.Dial()
+		}))
+		return grpc.NewClient(target, opts...)
+	}
+	t.Cleanup(func() {
+		grpcNewClient = grpc.NewClient
+	})
+	return servers
+}
+
+func TestNewGRPCClients_MultipleAddressesDistributeRequests(t *testing.T) {
+	backends := useBufconnBackends(t, []string{"llm-a", "llm-b"})
+
+	clients, err := NewGRPCClients([]ServiceConfig{{
+		name: "llm",
+		addr: "llm-a, llm-b",
+		newClient: func(conn *grpc.ClientConn) any {
+			return pb.NewLLMSummaryServiceClient(conn)
+		},
+	}})
+	require.NoError(t, err)
+	t.Cleanup(clients.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, clients.WaitForHealthy(ctx))
+
+	client := clients.GetClient("llm").(pb.LLMSummaryServiceClient)
+	for i := 0; i < 20; i++ {
+		_, err := client.Summarize(ctx, &pb.LLMSummaryRequest{}, grpc.WaitForReady(true))
+		require.NoError(t, err)
+	}
+
+	assert.Greater(t, backends["llm-a"].calls.Load(), int32(0))
+	assert.Greater(t, backends["llm-b"].calls.Load(), int32(0))
+	assert.Equal(t, int32(20), backends["llm-a"].calls.Load()+backends["llm-b"].calls.Load())
+}
+
+func TestNewGRPCClients_MultipleAddressesHealthCheckEachBackend(t *testing.T) {
+	useBufconnBackends(t, []string{"llm-a", "llm-b"}, "llm-b")
+
+	clients, err := NewGRPCClients([]ServiceConfig{{
+		name: "llm",
+		addr: "llm-a,llm-b",
+		newClient: func(conn *grpc.ClientConn) any {
+			return pb.NewLLMSummaryServiceClient(conn)
+		},
+	}})
+	require.NoError(t, err)
+	t.Cleanup(clients.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 700*time.Millisecond)
+	defer cancel()
+	err = clients.WaitForHealthy(ctx)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "health check timeout for llm (llm-b)")
+	assert.NotContains(t, err.Error(), "health check timeout for llm (llm-a)")
+	assert.Contains(t, err.Error(), "healthy in time: [llm (llm-a)]")
+}
+
+func TestNewGRPCClients_DialOptionsReachBufconn(t *testing.T) {
+	server := grpc.NewServer()
+	llmServer := &countingLLMServer{}
+	pb.RegisterLLMSummaryServiceServer(server, llmServer)
+	listener := bufconn.Listen(1024 * 1024)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	clients, err := NewGRPCClients([]ServiceConfig{{
+		name: "llm",
+		addr: "passthrough:///llm",
+		newClient: func(conn *grpc.ClientConn) any {
+			return pb.NewLLMSummaryServiceClient(conn)
+		},
+		dialOptions: []grpc.DialOption{grpc.WithContextDialer(testutils.BufDialer(listener))},
+	}})
+	require.NoError(t, err)
+	t.Cleanup(clients.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	client := clients.GetClient("llm").(pb.LLMSummaryServiceClient)
+	_, err = client.Summarize(ctx, &pb.LLMSummaryRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), llmServer.calls.Load())
+}
+
+func TestNewGRPCClients_ConnectTimeout(t *testing.T) {
+	newLLMConfig := func(addr string, dialOptions ...grpc.DialOption) ServiceConfig {
+		return ServiceConfig{
+			name:           "llm",
+			addr:           addr,
+			connectTimeout: 300 * time.Millisecond,
+			newClient: func(conn *grpc.ClientConn) any {
+				return pb.NewLLMSummaryServiceClient(conn)
+			},
+			dialOptions: dialOptions,
+		}
+	}
+
+	t.Run("fails fast naming the unreachable service", func(t *testing.T) {
+		// Grab a free port and close it so nothing is listening there
+		lis, err := net.Listen("tcp", "127.0.0.1:0")
+		require.NoError(t, err)
+		addr := lis.Addr().String()
+		require.NoError(t, lis.Close())
+
+		start := time.Now()
+		clients, err := NewGRPCClients([]ServiceConfig{newLLMConfig(addr)})
+		require.Error(t, err)
+		assert.Nil(t, clients)
+		assert.Less(t, time.Since(start), 3*time.Second)
+		assert.Contains(t, err.Error(), "failed to connect to llm server")
+		assert.Contains(t, err.Error(), addr+" not connected within 300ms")
+	})
+
+	t.Run("names the unreachable replica", func(t *testing.T) {
+		useBufconnBackends(t, []string{"llm-a"})
+
+		_, err := NewGRPCClients([]ServiceConfig{newLLMConfig("llm-a,llm-b")})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "llm-b not connected within 300ms")
+	})
+
+	t.Run("succeeds once connected", func(t *testing.T) {
+		listener := testutils.ServeBufconn(t, func(grpc.ServiceRegistrar) {})
+		clients, err := NewGRPCClients([]ServiceConfig{
+			newLLMConfig("passthrough:///llm", grpc.WithContextDialer(testutils.BufDialer(listener))),
+		})
+		require.NoError(t, err)
+		t.Cleanup(clients.Close)
+	})
+}
+
+func TestSetAndGetClients(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	t.Run("returns the stored clients", func(t *testing.T) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		clients := mocks.NewMockGRPCClients()
+		SetClients(c, clients)
+
+		got, err := GetClients(c)
+		require.NoError(t, err)
+		assert.Same(t, clients, got)
+	})
+
+	t.Run("miss returns an error instead of panicking", func(t *testing.T) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		_, err := GetClients(c)
+		assert.ErrorIs(t, err, errClientsNotSet)
+
+		c.Set(grpcClientsKey{}, "not a client provider")
+		_, err = GetClients(c)
+		assert.ErrorIs(t, err, errClientsNotSet)
+	})
+
+	t.Run("handlers answer 500 without clients", func(t *testing.T) {
+		router := gin.New()
+		router.GET("/api/summary", HandleSummary)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/summary", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), errClientsNotSet.Error())
+	})
+}
+
+func TestSplitAddrs(t *testing.T) {
+	assert.Equal(t, []string{"a:1", "b:2"}, splitAddrs(" a:1, ,b:2 "))
+	assert.Equal(t, []string{":50051"}, splitAddrs(":50051"))
+	assert.Nil(t, splitAddrs(""))
+}
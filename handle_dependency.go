@@ -7,7 +7,6 @@ import (
 
 	"github.com/gin-gonic/gin"
 	pb "github.com/ziyixi/protos/go/todofy"
-	"github.com/ziyixi/todofy/utils"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -146,7 +145,11 @@ func HandleDependencyIssues(c *gin.Context) {
 }
 
 func getDependencyClient(c *gin.Context) (pb.DependencyServiceClient, bool) {
-	clients := c.MustGet(utils.KeyGRPCClients).(ClientProvider)
+	clients, err := GetClients(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return nil, false
+	}
 	client := clients.GetClient("dependency")
 	if client == nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "dependency client not configured"})
@@ -10,7 +10,6 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/ziyixi/todofy/testutils/mocks"
-	"github.com/ziyixi/todofy/utils"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
@@ -30,7 +29,7 @@ func setupDependencyHandlerRouter(
 
 	router := gin.New()
 	router.Use(func(c *gin.Context) {
-		c.Set(utils.KeyGRPCClients, clients)
+		SetClients(c, clients)
 		c.Next()
 	})
 	router.POST("/dependency/reconcile", HandleDependencyReconcile)
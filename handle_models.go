@@ -0,0 +1,64 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ziyixi/todofy/utils"
+)
+
+// ModelInfo describes one model the LLM service accepts.
+type ModelInfo struct {
+	// ID is the pb.Model enum name to send as the request model.
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Priority is the 1-based position in the default fallback chain (0 = only used when requested).
+	Priority int `json:"priority,omitempty"`
+}
+
+// ModelsResponse is the JSON response of /api/models.
+type ModelsResponse struct {
+	ModelFamilies []string    `json:"model_families"`
+	Models        []ModelInfo `json:"models"`
+	DefaultModel  string      `json:"default_model"`
+}
+
+// HandleModels lists the supported model families and models, fallback chain first.
+func HandleModels(c *gin.Context) {
+	c.JSON(http.StatusOK, supportedModels())
+}
+
+func supportedModels() ModelsResponse {
+	resp := ModelsResponse{
+		ModelFamilies: make([]string, 0, len(utils.SupportedModelFamilies)),
+		Models:        make([]ModelInfo, 0, len(utils.LLMModelNames)),
+	}
+	for _, family := range utils.SupportedModelFamilies {
+		name := strings.TrimPrefix(family.String(), "MODEL_FAMILY_")
+		resp.ModelFamilies = append(resp.ModelFamilies, strings.ToLower(name))
+	}
+	if len(utils.LLMModelPriority) > 0 {
+		resp.DefaultModel = utils.ModelName(utils.LLMModelPriority[0])
+	}
+
+	priority := make(map[string]int, len(utils.LLMModelPriority))
+	for i, model := range utils.LLMModelPriority {
+		priority[model.String()] = i + 1
+	}
+	for model, name := range utils.LLMModelNames {
+		resp.Models = append(resp.Models, ModelInfo{ID: model.String(), Name: name, Priority: priority[model.String()]})
+	}
+	sort.Slice(resp.Models, func(i, j int) bool {
+		pi, pj := resp.Models[i].Priority, resp.Models[j].Priority
+		if (pi == 0) != (pj == 0) {
+			return pi != 0
+		}
+		if pi != pj {
+			return pi < pj
+		}
+		return resp.Models[i].Name < resp.Models[j].Name
+	})
+	return resp
+}
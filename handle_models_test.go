@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleModels(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/models", HandleModels)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/api/models", nil)
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp ModelsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	assert.Equal(t, []string{"gemini"}, resp.ModelFamilies)
+	assert.Equal(t, "gemini-2.5-flash-lite", resp.DefaultModel)
+	assert.Equal(t, []ModelInfo{
+		{ID: "MODEL_GEMINI_2_5_FLASH_LITE", Name: "gemini-2.5-flash-lite", Priority: 1},
+		{ID: "MODEL_GEMINI_2_5_FLASH", Name: "gemini-2.5-flash", Priority: 2},
+		{ID: "MODEL_GEMINI_3_FLASH_PREVIEW", Name: "gemini-3-flash-preview", Priority: 3},
+		{ID: "MODEL_GEMINI_2_5_PRO", Name: "gemini-2.5-pro"},
+	}, resp.Models)
+}
@@ -1,8 +1,12 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
@@ -10,6 +14,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/ziyixi/todofy/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 
 	pb "github.com/ziyixi/protos/go/todofy"
 )
@@ -18,20 +24,39 @@ const (
 	TimeDurationToRecommendation = 24 * time.Hour
 	DefaultTopN                  = 3
 	MaxTopN                      = 10
-	taskSummarySplitter          = "=========================\n"
 )
 
+// recommendationMaxEntries caps how many of the most recent tasks are sent to the LLM for ranking;
+// set from --recommendation-max-entries (0 sends every task).
+var recommendationMaxEntries int
+
 // LLM retry configuration — var so tests can override.
 var (
 	LLMMaxRetries = 3
 	LLMRetrySleep = 5 * time.Second
 )
 
+// Webhook delivery configuration — var so tests can override.
+var (
+	// recommendationWebhookURL receives ?notify=true results; set from --recommendation-webhook.
+	recommendationWebhookURL string
+	// webhookRetry applies to the recommendation and scheduled summary webhooks.
+	webhookRetry = utils.RetryConfig{
+		MaxAttempts: 3,
+		BaseDelay:   500 * time.Millisecond,
+		MaxDelay:    5 * time.Second,
+	}
+	webhookHTTPClient = &http.Client{Timeout: 10 * time.Second}
+)
+
 // TaskRecommendation represents a single recommended task entry.
+// CreatedAt/UpdatedAt (RFC3339) come from the stored task the title matches, when one does.
 type TaskRecommendation struct {
-	Rank   int    `json:"rank"`
-	Title  string `json:"title"`
-	Reason string `json:"reason"`
+	Rank      int    `json:"rank"`
+	Title     string `json:"title"`
+	Reason    string `json:"reason"`
+	CreatedAt string `json:"created_at,omitempty"`
+	UpdatedAt string `json:"updated_at,omitempty"`
 }
 
 // RecommendationResponse is the top-level JSON response.
@@ -44,9 +69,16 @@ type RecommendationResponse struct {
 // HandleRecommendation queries recent tasks from the last 24 hours,
 // asks the LLM to pick the top-N most important ones, and returns
 // the result as a structured JSON array for consumption by other apps.
-// Optional query parameter: ?top=N (default 3, max 10).
+// Optional query parameters: ?top=N (default 3, max 10), ?all=true to keep every
+// task the LLM returned instead of trimming to N, ?limit=N to rank the N most recent
+// tasks regardless of age instead of the last 24 hours, and ?notify=true to also POST
+// the response to the configured recommendation webhook.
 func HandleRecommendation(c *gin.Context) {
-	clients := c.MustGet(utils.KeyGRPCClients).(ClientProvider)
+	clients, err := GetClients(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
 	// Parse optional "top" query parameter
 	topN := DefaultTopN
@@ -54,56 +86,98 @@ func HandleRecommendation(c *gin.Context) {
 		if n, err := strconv.Atoi(topStr); err == nil && n >= 1 && n <= MaxTopN {
 			topN = n
 		} else {
+			// code and field let clients tell which parameter to fix without parsing the message
 			c.JSON(http.StatusBadRequest, gin.H{
-				"error": fmt.Sprintf(
-					"invalid top parameter: must be 1-%d", MaxTopN),
+				"error": fmt.Sprintf("invalid top parameter: must be 1-%d", MaxTopN),
+				"code":  "INVALID_TOP",
+				"field": "top",
 			})
 			return
 		}
 	}
 
+	returnAll := false
+	if allStr := c.Query("all"); allStr != "" {
+		var err error
+		if returnAll, err = strconv.ParseBool(allStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid all parameter: must be a boolean"})
+			return
+		}
+	}
+
+	notify := false
+	if notifyStr := c.Query("notify"); notifyStr != "" {
+		var err error
+		if notify, err = strconv.ParseBool(notifyStr); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid notify parameter: must be a boolean"})
+			return
+		}
+	}
+	entryLimit, err := parseEntryLimit(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if notify && recommendationWebhookURL == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "notify requested but no recommendation webhook is configured"})
+		return
+	}
+
 	// Query recent tasks from the database
 	databaseClient := clients.GetClient("database").(pb.DataBaseServiceClient)
-	queryReq := &pb.QueryRecentRequest{
-		Type:             pb.DatabaseType_DATABASE_TYPE_SQLITE,
-		TimeAgoInSeconds: int64(TimeDurationToRecommendation.Seconds()),
-	}
-	queryResp, err := databaseClient.QueryRecent(c, queryReq)
+	entries, err := queryEntries(c, databaseClient, TimeDurationToRecommendation, entryLimit)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
 		return
 	}
 
-	if len(queryResp.Entries) == 0 {
-		c.JSON(http.StatusOK, RecommendationResponse{
+	if len(entries) == 0 {
+		respondRecommendation(c, notify, RecommendationResponse{
 			Tasks:     []TaskRecommendation{},
 			TaskCount: 0,
 		})
 		return
 	}
 
-	// Build content from task summaries
-	content := taskSummarySplitter
-	for _, entry := range queryResp.Entries {
-		content += entry.Summary + "\n" + taskSummarySplitter
+	// Repeated notifications leave nothing to rank, so skip the LLM entirely
+	if task, ok := identicalEntriesRecommendation(entries); ok {
+		respondRecommendation(c, notify, RecommendationResponse{
+			Tasks:     []TaskRecommendation{task},
+			TaskCount: len(entries),
+		})
+		return
+	}
+
+	// Build content from task summaries, keeping the prompt within budget on busy days
+	ranked := newestEntries(entries, recommendationMaxEntries)
+	if len(ranked) < len(entries) {
+		log.Infof("Recommendation input capped to the %d most recent of %d tasks", len(ranked), len(entries))
 	}
+	content := buildEntriesContent(ranked, true)
 
 	// Generate recommendation via LLM
-	prompt := fmt.Sprintf(
-		utils.DefaultPromptToRecommendTopTasks,
-		topN, topN, topN, topN,
-	)
+	prompt, err := utils.FormatRecommendTopTasksPrompt(utils.DefaultPromptToRecommendTopTasks, topN)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if len(prioritySenders) > 0 {
+		prompt += "\n" + utils.PromptPrioritySenders
+	}
 	recReq := &pb.LLMSummaryRequest{
 		ModelFamily: pb.ModelFamily_MODEL_FAMILY_GEMINI,
 		Model:       utils.RecommendationModel,
-		Prompt:      prompt,
+		Prompt:      utils.WrapPrompt(entriesPrompt(prompt), promptPrefix, promptSuffix),
 		Text:        content,
 	}
 	llmClient := clients.GetClient("llm").(pb.LLMSummaryServiceClient)
 	var recResp *pb.LLMSummaryResponse
 	for attempt := 1; attempt <= LLMMaxRetries; attempt++ {
-		recResp, err = llmClient.Summarize(c, recReq)
-		if err == nil {
+		var trailer metadata.MD
+		recResp, err = llmClient.Summarize(c, recReq, grpc.Trailer(&trailer))
+		logLLMFallback("recommendation", trailer)
+		// Quota exhaustion and rejected requests fail the same way on retry
+		if err == nil || llmErrorHTTPStatus(err) != http.StatusInternalServerError {
 			break
 		}
 		log.Printf(
@@ -115,7 +189,7 @@ func HandleRecommendation(c *gin.Context) {
 		}
 	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(llmErrorHTTPStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -134,10 +208,147 @@ func HandleRecommendation(c *gin.Context) {
 			{Rank: 1, Title: "recommendation", Reason: recResp.Summary},
 		}
 	}
+	if !returnAll {
+		tasks = limitRecommendations(tasks, topN)
+	}
+	attachEntryTimestamps(tasks, entries)
 
-	c.JSON(http.StatusOK, RecommendationResponse{
+	respondRecommendation(c, notify, RecommendationResponse{
 		Tasks:     tasks,
-		Model:     recResp.Model.String(),
-		TaskCount: len(queryResp.Entries),
+		Model:     utils.ModelName(recResp.Model),
+		TaskCount: len(entries),
+	})
+}
+
+// identicalEntriesRecommendation returns a single recommendation when there are
+// several entries and all of them have exactly the same summary.
+func identicalEntriesRecommendation(entries []*pb.DataBaseSchema) (TaskRecommendation, bool) {
+	if len(entries) < 2 {
+		return TaskRecommendation{}, false
+	}
+	for _, entry := range entries[1:] {
+		if entry.Summary != entries[0].Summary {
+			return TaskRecommendation{}, false
+		}
+	}
+
+	title := storedSubject(entries[0].Summary)
+	if title == "" {
+		title = "recommendation"
+	}
+	latest := newestEntry(entries)
+	return TaskRecommendation{
+		Rank:      1,
+		Title:     title,
+		Reason:    fmt.Sprintf("All %d tasks in the window are the same repeated item", len(entries)),
+		CreatedAt: formatEntryTimestamp(latest.CreatedAt),
+		UpdatedAt: formatEntryTimestamp(latest.UpdatedAt),
+	}, true
+}
+
+// attachEntryTimestamps copies created/updated times onto each task whose title equals exactly
+// one stored subject, ignoring case and whitespace. Tasks matching no subject, or several, are
+// left without timestamps rather than guessing which entry they came from.
+func attachEntryTimestamps(tasks []TaskRecommendation, entries []*pb.DataBaseSchema) {
+	for i := range tasks {
+		title := normalizeTitle(tasks[i].Title)
+		if title == "" {
+			continue
+		}
+		var matches []*pb.DataBaseSchema
+		for _, entry := range entries {
+			if normalizeTitle(storedSubject(entry.Summary)) == title {
+				matches = append(matches, entry)
+			}
+		}
+		if len(matches) != 1 {
+			continue
+		}
+		tasks[i].CreatedAt = formatEntryTimestamp(matches[0].CreatedAt)
+		tasks[i].UpdatedAt = formatEntryTimestamp(matches[0].UpdatedAt)
+	}
+}
+
+// normalizeTitle lowercases s and collapses its whitespace for title/subject comparison.
+func normalizeTitle(s string) string {
+	return strings.ToLower(strings.Join(strings.Fields(s), " "))
+}
+
+// newestEntry returns the most recently created of the non-empty entries.
+func newestEntry(entries []*pb.DataBaseSchema) *pb.DataBaseSchema {
+	latest := entries[0]
+	for _, entry := range entries[1:] {
+		if entry.CreatedAt.AsTime().After(latest.CreatedAt.AsTime()) {
+			latest = entry
+		}
+	}
+	return latest
+}
+
+// limitRecommendations keeps the first topN tasks and renumbers their ranks 1..N,
+// so the "top N" contract holds even when the LLM returns more or misnumbers them.
+func limitRecommendations(tasks []TaskRecommendation, topN int) []TaskRecommendation {
+	if len(tasks) > topN {
+		tasks = tasks[:topN]
+	}
+	for i := range tasks {
+		tasks[i].Rank = i + 1
+	}
+	return tasks
+}
+
+// respondRecommendation writes resp, first delivering it to the webhook when notify is set.
+func respondRecommendation(c *gin.Context, notify bool, resp RecommendationResponse) {
+	if notify {
+		if err := postWebhook(c, recommendationWebhookURL, resp); err != nil {
+			c.JSON(http.StatusBadGateway, gin.H{
+				"error": fmt.Sprintf("failed to deliver recommendation webhook: %v", err),
+			})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+type webhookStatusError struct {
+	statusCode int
+}
+
+func (e *webhookStatusError) Error() string {
+	return fmt.Sprintf("webhook responded with status %d", e.statusCode)
+}
+
+// postWebhook POSTs body as JSON to url, retrying transport errors, 429 and 5xx responses.
+func postWebhook(ctx context.Context, url string, body any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+
+	return utils.Retry(ctx, webhookRetry, func(int) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+
+		httpResp, err := webhookHTTPClient.Do(req)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			_ = httpResp.Body.Close()
+		}()
+		_, _ = io.Copy(io.Discard, httpResp.Body)
+		if httpResp.StatusCode < 200 || httpResp.StatusCode >= 300 {
+			return &webhookStatusError{statusCode: httpResp.StatusCode}
+		}
+		return nil
+	}, func(err error, _ int) (bool, time.Duration) {
+		var statusErr *webhookStatusError
+		if errors.As(err, &statusErr) {
+			return statusErr.statusCode == http.StatusTooManyRequests || statusErr.statusCode >= 500, 0
+		}
+		return true, 0
 	})
 }
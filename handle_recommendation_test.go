@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -15,6 +16,9 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/ziyixi/todofy/testutils/mocks"
 	"github.com/ziyixi/todofy/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	pb "github.com/ziyixi/protos/go/todofy"
 )
@@ -34,7 +38,7 @@ func setupRecommendationTest(
 
 	router := gin.New()
 	router.Use(func(c *gin.Context) {
-		c.Set(utils.KeyGRPCClients, clients)
+		SetClients(c, clients)
 		c.Next()
 	})
 	router.GET("/api/recommendation", HandleRecommendation)
@@ -69,6 +73,21 @@ func TestHandleRecommendation_NoTasks(t *testing.T) {
 	mockDB.AssertExpectations(t)
 }
 
+func TestHandleRecommendation_QueriesConfiguredDatabaseType(t *testing.T) {
+	useDatabaseType(t, testDatabaseType)
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockDB.On("QueryRecent", mock.Anything, mock.MatchedBy(func(req *pb.QueryRecentRequest) bool {
+		return req.Type == testDatabaseType
+	}), mock.Anything).Return(&pb.QueryRecentResponse{Entries: []*pb.DataBaseSchema{}}, nil)
+
+	w, router := setupRecommendationTest(mockDB, nil)
+	req, _ := http.NewRequest(http.MethodGet, "/api/recommendation", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockDB.AssertExpectations(t)
+}
+
 func TestHandleRecommendation_DatabaseError(t *testing.T) {
 	mockDB := new(mocks.MockDataBaseServiceClient)
 	mockDB.On("QueryRecent", mock.Anything, mock.Anything, mock.Anything).
@@ -108,6 +127,67 @@ func TestHandleRecommendation_LLMError(t *testing.T) {
 	mockLLM.AssertExpectations(t)
 }
 
+func TestHandleRecommendation_IncludesEntryMetadata(t *testing.T) {
+	created := time.Date(2024, 3, 5, 9, 30, 0, 0, time.UTC)
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockDB.On("QueryRecent", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.QueryRecentResponse{
+			Entries: []*pb.DataBaseSchema{
+				{Summary: "**SUBJECT: Ship release**\ntask", CreatedAt: timestamppb.New(created)},
+			},
+		}, nil)
+
+	header := "[" + created.Local().Format(entryHeaderDateLayout) + "] Ship release:"
+	mockLLM := new(mocks.MockLLMSummaryServiceClient)
+	mockLLM.On("Summarize", mock.Anything, mock.MatchedBy(func(req *pb.LLMSummaryRequest) bool {
+		return strings.Contains(req.Text, header)
+	}), mock.Anything).
+		Return(&pb.LLMSummaryResponse{Summary: `[{"rank":1,"title":"Ship release","reason":"due"}]`}, nil)
+
+	w, router := setupRecommendationTest(mockDB, mockLLM)
+	req, _ := http.NewRequest(http.MethodGet, "/api/recommendation", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockLLM.AssertExpectations(t)
+}
+
+func TestHandleRecommendation_IdenticalSummariesSkipLLM(t *testing.T) {
+	stored := "**SUBJECT: Disk usage alert**\nThe disk is almost full."
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockDB.On("QueryRecent", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.QueryRecentResponse{
+			Entries: []*pb.DataBaseSchema{{Summary: stored}, {Summary: stored}, {Summary: stored}, {Summary: stored}},
+		}, nil)
+	mockLLM := new(mocks.MockLLMSummaryServiceClient)
+
+	w, router := setupRecommendationTest(mockDB, mockLLM)
+	req, _ := http.NewRequest(http.MethodGet, "/api/recommendation", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp RecommendationResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Tasks, 1)
+	assert.Equal(t, 1, resp.Tasks[0].Rank)
+	assert.Equal(t, "Disk usage alert", resp.Tasks[0].Title)
+	assert.Contains(t, resp.Tasks[0].Reason, "All 4 tasks")
+	assert.Equal(t, 4, resp.TaskCount)
+	mockLLM.AssertNotCalled(t, "Summarize", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestIdenticalEntriesRecommendation(t *testing.T) {
+	_, ok := identicalEntriesRecommendation([]*pb.DataBaseSchema{{Summary: "a"}})
+	assert.False(t, ok, "a single entry still goes to the LLM")
+
+	_, ok = identicalEntriesRecommendation([]*pb.DataBaseSchema{{Summary: "a"}, {Summary: "a"}, {Summary: "b"}})
+	assert.False(t, ok)
+
+	task, ok := identicalEntriesRecommendation([]*pb.DataBaseSchema{{Summary: "a"}, {Summary: "a"}})
+	assert.True(t, ok)
+	assert.Equal(t, "recommendation", task.Title)
+}
+
 func TestHandleRecommendation_ValidJSON(t *testing.T) {
 	llmJSON := `[{"rank":1,"title":"重要任务A","reason":"需要立即处理"},` +
 		`{"rank":2,"title":"任务B","reason":"截止日期临近"},` +
@@ -140,7 +220,7 @@ func TestHandleRecommendation_ValidJSON(t *testing.T) {
 	var resp RecommendationResponse
 	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
 	assert.Equal(t, 4, resp.TaskCount)
-	assert.Equal(t, "MODEL_GEMINI_2_5_FLASH_LITE", resp.Model)
+	assert.Equal(t, "gemini-2.5-flash-lite", resp.Model)
 	require.Len(t, resp.Tasks, 3)
 
 	// Verify each task has the correct rank, title, and reason
@@ -325,6 +405,51 @@ func TestHandleRecommendation_VerifiesPromptSent(t *testing.T) {
 	mockLLM.AssertExpectations(t)
 }
 
+func TestHandleRecommendation_PromptPrefixAndSuffix(t *testing.T) {
+	usePromptWrap(t, "Be brief.", "Always mention deadlines.")
+
+	mockDB, _ := newRecommendationMocks()
+	basePrompt, err := utils.FormatRecommendTopTasksPrompt(utils.DefaultPromptToRecommendTopTasks, DefaultTopN)
+	require.NoError(t, err)
+	mockLLM := new(mocks.MockLLMSummaryServiceClient)
+	mockLLM.On("Summarize", mock.Anything, mock.MatchedBy(func(req *pb.LLMSummaryRequest) bool {
+		return req.Prompt == "Be brief.\n\n"+basePrompt+"\n\nAlways mention deadlines."
+	}), mock.Anything).
+		Return(&pb.LLMSummaryResponse{Summary: `[{"rank":1,"title":"T","reason":"R"}]`}, nil)
+
+	w, router := setupRecommendationTest(mockDB, mockLLM)
+	req, _ := http.NewRequest(http.MethodGet, "/api/recommendation", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockLLM.AssertExpectations(t)
+}
+
+func TestHandleRecommendation_PrioritySenders(t *testing.T) {
+	usePrioritySenders(t, "boss@example.com")
+
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockDB.On("QueryRecent", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.QueryRecentResponse{Entries: []*pb.DataBaseSchema{
+			{Summary: "**FROM: news@example.com**\n**SUBJECT: Sale**\nbody"},
+			{Summary: "**FROM: boss@example.com**\n**SUBJECT: Review**\nbody"},
+		}}, nil)
+	mockLLM := new(mocks.MockLLMSummaryServiceClient)
+	mockLLM.On("Summarize", mock.Anything, mock.MatchedBy(func(req *pb.LLMSummaryRequest) bool {
+		return strings.HasSuffix(req.Prompt, "\n"+utils.PromptPrioritySenders) &&
+			strings.Contains(req.Text, utils.PrioritySenderMarker+" Review:") &&
+			!strings.Contains(req.Text, utils.PrioritySenderMarker+" Sale:")
+	}), mock.Anything).
+		Return(&pb.LLMSummaryResponse{Summary: `[{"rank":1,"title":"T","reason":"R"}]`}, nil)
+
+	w, router := setupRecommendationTest(mockDB, mockLLM)
+	req, _ := http.NewRequest(http.MethodGet, "/api/recommendation", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockLLM.AssertExpectations(t)
+}
+
 func TestHandleRecommendation_TaskCountMatchesDBEntries(t *testing.T) {
 	// task_count should reflect DB entries, not parsed tasks
 	llmJSON := `[{"rank":1,"title":"T1","reason":"R1"},` +
@@ -430,6 +555,60 @@ func TestHandleRecommendation_TopParamCustomValue(t *testing.T) {
 	mockLLM.AssertExpectations(t)
 }
 
+func TestHandleRecommendation_TrimsOverReturnedTasks(t *testing.T) {
+	llmJSON := `[{"rank":1,"title":"T1","reason":"R1"},` +
+		`{"rank":3,"title":"T2","reason":"R2"},` +
+		`{"rank":4,"title":"T3","reason":"R3"},` +
+		`{"rank":5,"title":"T4","reason":"R4"},` +
+		`{"rank":6,"title":"T5","reason":"R5"}]`
+
+	tests := []struct {
+		name      string
+		query     string
+		wantRanks []int
+	}{
+		{name: "trims to top and renumbers", query: "?top=2", wantRanks: []int{1, 2}},
+		{name: "all keeps every task as returned", query: "?top=2&all=true", wantRanks: []int{1, 3, 4, 5, 6}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := new(mocks.MockDataBaseServiceClient)
+			mockDB.On("QueryRecent", mock.Anything, mock.Anything, mock.Anything).
+				Return(&pb.QueryRecentResponse{
+					Entries: []*pb.DataBaseSchema{{Summary: "a"}},
+				}, nil)
+			mockLLM := new(mocks.MockLLMSummaryServiceClient)
+			mockLLM.On("Summarize", mock.Anything, mock.Anything, mock.Anything).
+				Return(&pb.LLMSummaryResponse{Summary: llmJSON}, nil)
+
+			w, router := setupRecommendationTest(mockDB, mockLLM)
+			req, _ := http.NewRequest(http.MethodGet, "/api/recommendation"+tt.query, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			var resp RecommendationResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			ranks := make([]int, 0, len(resp.Tasks))
+			for _, task := range resp.Tasks {
+				ranks = append(ranks, task.Rank)
+			}
+			assert.Equal(t, tt.wantRanks, ranks)
+			assert.Equal(t, "T1", resp.Tasks[0].Title)
+			assert.Equal(t, "T2", resp.Tasks[1].Title)
+		})
+	}
+}
+
+func TestHandleRecommendation_InvalidAllParam(t *testing.T) {
+	w, router := setupRecommendationTest(new(mocks.MockDataBaseServiceClient), nil)
+	req, _ := http.NewRequest(http.MethodGet, "/api/recommendation?all=maybe", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid all parameter")
+}
+
 func TestHandleRecommendation_TopParamInvalid(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -453,7 +632,13 @@ func TestHandleRecommendation_TopParamInvalid(t *testing.T) {
 			router.ServeHTTP(w, req)
 
 			assert.Equal(t, http.StatusBadRequest, w.Code)
-			assert.Contains(t, w.Body.String(), "invalid top")
+			var body map[string]any
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, map[string]any{
+				"error": "invalid top parameter: must be 1-10",
+				"code":  "INVALID_TOP",
+				"field": "top",
+			}, body)
 		})
 	}
 }
@@ -544,3 +729,286 @@ func TestHandleRecommendation_TopParamBoundary(t *testing.T) {
 		})
 	}
 }
+
+func useRecommendationWebhook(t *testing.T, url string) {
+	t.Helper()
+	originalURL := recommendationWebhookURL
+	originalRetry := webhookRetry
+	recommendationWebhookURL = url
+	webhookRetry = utils.RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	t.Cleanup(func() {
+		recommendationWebhookURL = originalURL
+		webhookRetry = originalRetry
+	})
+}
+
+func newRecommendationMocks() (*mocks.MockDataBaseServiceClient, *mocks.MockLLMSummaryServiceClient) {
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockDB.On("QueryRecent", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.QueryRecentResponse{
+			Entries: []*pb.DataBaseSchema{{Summary: "summary1"}, {Summary: "summary2"}},
+		}, nil)
+	mockLLM := new(mocks.MockLLMSummaryServiceClient)
+	mockLLM.On("Summarize", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.LLMSummaryResponse{
+			Summary: `[{"rank":1,"title":"T1","reason":"R1"}]`,
+			Model:   pb.Model_MODEL_GEMINI_2_5_FLASH,
+		}, nil)
+	return mockDB, mockLLM
+}
+
+func TestHandleRecommendation_NotifyPostsToWebhook(t *testing.T) {
+	var received []map[string]any
+	var attempts int
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		if attempts == 1 {
+			// First delivery fails transiently and must be retried
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var payload map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&payload))
+		received = append(received, payload)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer webhook.Close()
+	useRecommendationWebhook(t, webhook.URL)
+
+	mockDB, mockLLM := newRecommendationMocks()
+	w, router := setupRecommendationTest(mockDB, mockLLM)
+	req, _ := http.NewRequest(http.MethodGet, "/api/recommendation?notify=true", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, attempts)
+	require.Len(t, received, 1)
+	assert.Equal(t, float64(2), received[0]["task_count"])
+	assert.Equal(t, "gemini-2.5-flash", received[0]["model"])
+	tasks, ok := received[0]["tasks"].([]any)
+	require.True(t, ok)
+	require.Len(t, tasks, 1)
+	assert.Equal(t, map[string]any{"rank": float64(1), "title": "T1", "reason": "R1"}, tasks[0])
+}
+
+func TestHandleRecommendation_NotifyWebhookFailure(t *testing.T) {
+	var attempts int
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer webhook.Close()
+	useRecommendationWebhook(t, webhook.URL)
+
+	mockDB, mockLLM := newRecommendationMocks()
+	w, router := setupRecommendationTest(mockDB, mockLLM)
+	req, _ := http.NewRequest(http.MethodGet, "/api/recommendation?notify=true", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadGateway, w.Code)
+	assert.Contains(t, w.Body.String(), "status 400")
+	assert.Equal(t, 1, attempts, "4xx responses should not be retried")
+}
+
+func TestHandleRecommendation_NotifyValidation(t *testing.T) {
+	t.Run("webhook not configured", func(t *testing.T) {
+		useRecommendationWebhook(t, "")
+		w, router := setupRecommendationTest(new(mocks.MockDataBaseServiceClient), nil)
+		req, _ := http.NewRequest(http.MethodGet, "/api/recommendation?notify=true", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "no recommendation webhook is configured")
+	})
+
+	t.Run("invalid notify value", func(t *testing.T) {
+		w, router := setupRecommendationTest(new(mocks.MockDataBaseServiceClient), nil)
+		req, _ := http.NewRequest(http.MethodGet, "/api/recommendation?notify=maybe", nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid notify parameter")
+	})
+}
+
+func TestHandleRecommendation_LLMStatusMapping(t *testing.T) {
+	origSleep := LLMRetrySleep
+	LLMRetrySleep = 0
+	t.Cleanup(func() { LLMRetrySleep = origSleep })
+
+	tests := []struct {
+		name      string
+		err       error
+		wantCode  int
+		wantCalls int
+	}{
+		{
+			"token limit", status.Error(codes.ResourceExhausted, "daily token limit exceeded"),
+			http.StatusTooManyRequests, 1,
+		},
+		{"invalid argument", status.Error(codes.InvalidArgument, "unsupported model"), http.StatusBadRequest, 1},
+		{
+			"generation failure", status.Error(codes.Internal, "all models failed"),
+			http.StatusInternalServerError, LLMMaxRetries,
+		},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDB := new(mocks.MockDataBaseServiceClient)
+			mockDB.On("QueryRecent", mock.Anything, mock.Anything, mock.Anything).
+				Return(&pb.QueryRecentResponse{
+					Entries: []*pb.DataBaseSchema{{Summary: "task A"}},
+				}, nil)
+			mockLLM := new(mocks.MockLLMSummaryServiceClient)
+			mockLLM.On("Summarize", mock.Anything, mock.Anything, mock.Anything).Return(nil, tc.err)
+
+			w, router := setupRecommendationTest(mockDB, mockLLM)
+			req, _ := http.NewRequest(http.MethodGet, "/api/recommendation", nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantCode, w.Code)
+			// Only generation failures are worth retrying
+			mockLLM.AssertNumberOfCalls(t, "Summarize", tc.wantCalls)
+		})
+	}
+}
+
+func TestHandleRecommendation_IncludesEntryTimestamps(t *testing.T) {
+	created := time.Date(2024, 3, 5, 9, 30, 0, 0, time.UTC)
+	updated := created.Add(time.Hour)
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockDB.On("QueryRecent", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.QueryRecentResponse{
+			Entries: []*pb.DataBaseSchema{
+				{
+					Summary:   "**SUBJECT: Ship release**\ntask",
+					CreatedAt: timestamppb.New(created),
+					UpdatedAt: timestamppb.New(updated),
+				},
+				{Summary: "**SUBJECT: Book flights**\ntask", CreatedAt: timestamppb.New(created)},
+			},
+		}, nil)
+	mockLLM := new(mocks.MockLLMSummaryServiceClient)
+	mockLLM.On("Summarize", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.LLMSummaryResponse{
+			Summary: `[{"rank":1,"title":"ship release","reason":"due"},{"rank":2,"title":"Unrelated","reason":"?"}]`,
+		}, nil)
+
+	w, router := setupRecommendationTest(mockDB, mockLLM)
+	req, _ := http.NewRequest(http.MethodGet, "/api/recommendation", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp RecommendationResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Tasks, 2)
+	assert.Equal(t, "2024-03-05T09:30:00Z", resp.Tasks[0].CreatedAt)
+	assert.Equal(t, "2024-03-05T10:30:00Z", resp.Tasks[0].UpdatedAt)
+	assert.Empty(t, resp.Tasks[1].CreatedAt, "tasks that match no stored subject carry no timestamps")
+	assert.NotContains(t, w.Body.String(), `"created_at":""`)
+}
+
+func TestAttachEntryTimestamps(t *testing.T) {
+	created := time.Date(2024, 3, 5, 9, 30, 0, 0, time.UTC)
+	entries := []*pb.DataBaseSchema{
+		{Summary: "**SUBJECT: Ship  Release**\ntask", CreatedAt: timestamppb.New(created)},
+		{Summary: "**SUBJECT: Invoice**\na", CreatedAt: timestamppb.New(created)},
+		{Summary: "**SUBJECT: Invoice**\nb", CreatedAt: timestamppb.New(created.Add(time.Hour))},
+	}
+	tasks := []TaskRecommendation{
+		{Title: " ship release "},
+		{Title: "Ship release notes"},
+		{Title: "Ship"},
+		{Title: "invoice"},
+	}
+
+	attachEntryTimestamps(tasks, entries)
+	assert.Equal(t, "2024-03-05T09:30:00Z", tasks[0].CreatedAt, "equal after normalizing case and whitespace")
+	assert.Empty(t, tasks[1].CreatedAt, "a title containing the subject is not a match")
+	assert.Empty(t, tasks[2].CreatedAt, "a title contained in the subject is not a match")
+	assert.Empty(t, tasks[3].CreatedAt, "a title matching several entries is ambiguous")
+}
+
+func TestIdenticalEntriesRecommendation_UsesNewestTimestamps(t *testing.T) {
+	older := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	newer := older.Add(2 * time.Hour)
+	task, ok := identicalEntriesRecommendation([]*pb.DataBaseSchema{
+		{Summary: "a", CreatedAt: timestamppb.New(newer), UpdatedAt: timestamppb.New(newer)},
+		{Summary: "a", CreatedAt: timestamppb.New(older), UpdatedAt: timestamppb.New(older)},
+	})
+	require.True(t, ok)
+	assert.Equal(t, "2024-03-05T11:00:00Z", task.CreatedAt)
+	assert.Equal(t, "2024-03-05T11:00:00Z", task.UpdatedAt)
+}
+
+func TestHandleRecommendation_EntryLimit(t *testing.T) {
+	base := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockDB.On("QueryRecent", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.QueryRecentResponse{
+			Entries: []*pb.DataBaseSchema{
+				{Summary: "**SUBJECT: newest**", CreatedAt: timestamppb.New(base.Add(2 * time.Hour))},
+				{Summary: "**SUBJECT: oldest**", CreatedAt: timestamppb.New(base)},
+				{Summary: "**SUBJECT: middle**", CreatedAt: timestamppb.New(base.Add(time.Hour))},
+			},
+		}, nil)
+	mockLLM := new(mocks.MockLLMSummaryServiceClient)
+	mockLLM.On("Summarize", mock.Anything, mock.MatchedBy(func(req *pb.LLMSummaryRequest) bool {
+		return strings.Contains(req.Text, "newest") && strings.Contains(req.Text, "middle") &&
+			!strings.Contains(req.Text, "oldest")
+	}), mock.Anything).
+		Return(&pb.LLMSummaryResponse{Summary: `[{"rank":1,"title":"newest","reason":"x"}]`}, nil)
+
+	w, router := setupRecommendationTest(mockDB, mockLLM)
+	req, _ := http.NewRequest(http.MethodGet, "/api/recommendation?limit=2", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var resp RecommendationResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 2, resp.TaskCount)
+	mockLLM.AssertExpectations(t)
+}
+
+func TestHandleRecommendation_InvalidEntryLimit(t *testing.T) {
+	w, router := setupRecommendationTest(new(mocks.MockDataBaseServiceClient), nil)
+	req, _ := http.NewRequest(http.MethodGet, "/api/recommendation?limit=0", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid limit parameter")
+}
+
+func TestHandleRecommendation_MaxEntriesKeepsMostRecent(t *testing.T) {
+	original := recommendationMaxEntries
+	recommendationMaxEntries = 2
+	t.Cleanup(func() { recommendationMaxEntries = original })
+
+	now := time.Now()
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockDB.On("QueryRecent", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.QueryRecentResponse{Entries: []*pb.DataBaseSchema{
+			{Summary: "newest", CreatedAt: timestamppb.New(now.Add(-time.Hour))},
+			{Summary: "oldest", CreatedAt: timestamppb.New(now.Add(-20 * time.Hour))},
+			{Summary: "older", CreatedAt: timestamppb.New(now.Add(-10 * time.Hour))},
+			{Summary: "recent", CreatedAt: timestamppb.New(now.Add(-2 * time.Hour))},
+		}}, nil)
+	mockLLM := new(mocks.MockLLMSummaryServiceClient)
+	mockLLM.On("Summarize", mock.Anything, mock.MatchedBy(func(req *pb.LLMSummaryRequest) bool {
+		return strings.Index(req.Text, "recent") < strings.Index(req.Text, "newest") &&
+			!strings.Contains(req.Text, "older") && !strings.Contains(req.Text, "oldest")
+	}), mock.Anything).
+		Return(&pb.LLMSummaryResponse{Summary: `[{"rank":1,"title":"T","reason":"R"}]`}, nil)
+
+	w, router := setupRecommendationTest(mockDB, mockLLM)
+	req, _ := http.NewRequest(http.MethodGet, "/api/recommendation", nil)
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	mockLLM.AssertExpectations(t)
+	var resp RecommendationResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, 4, resp.TaskCount)
+}
@@ -1,63 +1,147 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ziyixi/todofy/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 
 	pb "github.com/ziyixi/protos/go/todofy"
 )
 
 const (
 	TimeDurationToSummary = 24 * time.Hour // 24 hours
+
+	defaultSummaryEmptyMessage = "As there is no new task in the last 24 hours, there will have no summary. " +
+		"Please check your service as it's highly not possible that there is no new task in the last 24 hours.\n"
 )
 
+// Empty-window behavior — set from --summary-empty-message and --summary-skip-empty.
+var (
+	summaryEmptyMessage = defaultSummaryEmptyMessage
+	// summarySkipEmpty makes HandleSummary answer 204 (and the scheduler skip) when there are no tasks,
+	// so the caller mailing the digest has nothing to send.
+	summarySkipEmpty bool
+	// summaryCategories replaces utils.DefaultSummaryCategories in the summary prompt when set.
+	summaryCategories []string
+)
+
+// summaryError tags a generateSummary failure with the key HandleSummary reports it under
+// and the HTTP status it maps to (500 when zero).
+type summaryError struct {
+	key        string
+	err        error
+	statusCode int
+}
+
+func (e *summaryError) Error() string {
+	return e.key + ": " + e.err.Error()
+}
+
+func (e *summaryError) Unwrap() error {
+	return e.err
+}
+
 // HandleSummary returns a 24-hour summary generated from recent persisted task entries.
+// ?format=plain converts the summary from markdown to plain text for plain-text mail, and
+// ?limit=N summarizes the N most recent entries instead, however old they are.
 func HandleSummary(c *gin.Context) {
-	clients := c.MustGet(utils.KeyGRPCClients).(ClientProvider)
+	clients, err := GetClients(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 
-	// Query all the data from the database
-	databaseClient := clients.GetClient("database").(pb.DataBaseServiceClient)
-	queryReq := &pb.QueryRecentRequest{
-		Type:             pb.DatabaseType_DATABASE_TYPE_SQLITE,
-		TimeAgoInSeconds: int64(TimeDurationToSummary.Seconds()),
+	format := c.DefaultQuery("format", "markdown")
+	if format != "markdown" && format != "plain" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid format parameter: must be markdown or plain"})
+		return
 	}
-	queryResp, err := databaseClient.QueryRecent(c, queryReq)
+
+	limit, err := parseEntryLimit(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error in querying database": err.Error()})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Build content for the summary
-	splitter := "=========================\n"
-	content := splitter
-	for _, entry := range queryResp.Entries {
-		content += entry.Summary + "\n" + splitter
+	summaries, taskCount, err := generateSummary(c, clients, limit)
+	if err != nil {
+		var sumErr *summaryError
+		if errors.As(err, &sumErr) {
+			statusCode := http.StatusInternalServerError
+			if sumErr.statusCode != 0 {
+				statusCode = sumErr.statusCode
+			}
+			c.JSON(statusCode, gin.H{sumErr.key: sumErr.err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	if taskCount == 0 && summarySkipEmpty {
+		c.Status(http.StatusNoContent)
+		return
+	}
+	if format == "plain" {
+		summaries = utils.MarkdownToPlainText(summaries)
+	}
+
+	c.JSON(http.StatusOK, summaryResponse(summaries, taskCount, limit))
+}
+
+// summaryResponse is the summary JSON returned by HandleSummary and posted to --summary-webhook.
+func summaryResponse(summary string, taskCount, limit int) gin.H {
+	resp := gin.H{
+		"summary":           summary,
+		"task_count":        taskCount,
+		"time_window_hours": int(TimeDurationToSummary / time.Hour),
+	}
+	if limit > 0 {
+		resp["entry_limit"] = limit
+	}
+	return resp
+}
+
+// generateSummary summarizes the last TimeDurationToSummary of persisted entries, or the
+// limit most recent ones when limit > 0. It backs both HandleSummary and the internal
+// summary scheduler.
+func generateSummary(ctx context.Context, clients ClientProvider, limit int) (string, int, error) {
+	databaseClient := clients.GetClient("database").(pb.DataBaseServiceClient)
+	entries, err := queryEntries(ctx, databaseClient, TimeDurationToSummary, limit)
+	if err != nil {
+		return "", 0, &summaryError{key: "error in querying database", err: err}
 	}
 
+	// Build content for the summary
+	content := buildEntriesContent(entries, false)
+
 	// Summarize the content
-	summaries := "As there is no new task in the last 24 hours, there will have no summary. " +
-		"Please check your service as it's highly not possible that there is no new task in the last 24 hours.\n"
-	if len(queryResp.Entries) > 0 {
+	summaries := summaryEmptyMessage
+	if len(entries) > 0 {
+		prompt := utils.FormatSummaryRangePrompt(summaryCategories)
 		summaryReq := &pb.LLMSummaryRequest{
 			ModelFamily: pb.ModelFamily_MODEL_FAMILY_GEMINI,
-			Prompt:      utils.DefaultPromptToSummaryEmailRange,
+			Prompt:      utils.WrapPrompt(entriesPrompt(prompt), promptPrefix, promptSuffix),
 			Text:        content,
 		}
 		llmClient := clients.GetClient("llm").(pb.LLMSummaryServiceClient)
-		summaryResp, err := llmClient.Summarize(c, summaryReq)
+		var trailer metadata.MD
+		summaryResp, err := llmClient.Summarize(ctx, summaryReq, grpc.Trailer(&trailer))
+		logLLMFallback("summary", trailer)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error in summarizing email": err.Error()})
-			return
+			return "", 0, &summaryError{
+				key:        "error in summarizing email",
+				err:        err,
+				statusCode: llmErrorHTTPStatus(err),
+			}
 		}
 		summaries = summaryResp.Summary
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"summary":           summaries,
-		"task_count":        len(queryResp.Entries),
-		"time_window_hours": int(TimeDurationToSummary / time.Hour),
-	})
+	return summaries, len(entries), nil
 }
@@ -5,14 +5,19 @@ import (
 	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/ziyixi/todofy/testutils/mocks"
 	"github.com/ziyixi/todofy/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	pb "github.com/ziyixi/protos/go/todofy"
 )
@@ -32,7 +37,7 @@ func setupSummaryTest(
 
 	router := gin.New()
 	router.Use(func(c *gin.Context) {
-		c.Set(utils.KeyGRPCClients, clients)
+		SetClients(c, clients)
 		c.Next()
 	})
 	router.GET("/api/summary", HandleSummary)
@@ -80,6 +85,60 @@ func TestHandleSummary_SuccessWithEntries(t *testing.T) {
 	mockLLM.AssertExpectations(t)
 }
 
+func TestHandleSummary_QueriesConfiguredDatabaseType(t *testing.T) {
+	useDatabaseType(t, testDatabaseType)
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockDB.On("QueryRecent", mock.Anything, mock.MatchedBy(func(req *pb.QueryRecentRequest) bool {
+		return req.Type == testDatabaseType
+	}), mock.Anything).Return(&pb.QueryRecentResponse{Entries: []*pb.DataBaseSchema{}}, nil)
+
+	w, router := setupSummaryTest(mockDB, nil)
+	req, _ := http.NewRequest(http.MethodGet, "/api/summary", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockDB.AssertExpectations(t)
+}
+
+func useSummaryEmptyBehavior(t *testing.T, message string, skip bool) {
+	t.Helper()
+	originalMessage, originalSkip := summaryEmptyMessage, summarySkipEmpty
+	summaryEmptyMessage, summarySkipEmpty = message, skip
+	t.Cleanup(func() {
+		summaryEmptyMessage, summarySkipEmpty = originalMessage, originalSkip
+	})
+}
+
+func TestHandleSummary_NoEntriesCustomMessage(t *testing.T) {
+	useSummaryEmptyBehavior(t, "过去 24 小时没有新任务。", false)
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockDB.On("QueryRecent", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.QueryRecentResponse{Entries: []*pb.DataBaseSchema{}}, nil)
+
+	w, router := setupSummaryTest(mockDB, nil)
+	req, _ := http.NewRequest(http.MethodGet, "/api/summary", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "过去 24 小时没有新任务。", body["summary"])
+}
+
+func TestHandleSummary_NoEntriesSkipEmpty(t *testing.T) {
+	useSummaryEmptyBehavior(t, defaultSummaryEmptyMessage, true)
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockDB.On("QueryRecent", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.QueryRecentResponse{Entries: []*pb.DataBaseSchema{}}, nil)
+
+	w, router := setupSummaryTest(mockDB, nil)
+	req, _ := http.NewRequest(http.MethodGet, "/api/summary", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+}
+
 func TestHandleSummary_SuccessNoEntries(t *testing.T) {
 	mockDB := new(mocks.MockDataBaseServiceClient)
 	mockDB.On("QueryRecent", mock.Anything, mock.Anything, mock.Anything).
@@ -135,6 +194,53 @@ func TestHandleSummary_LLMError(t *testing.T) {
 	mockLLM.AssertExpectations(t)
 }
 
+func TestHandleSummary_IncludesEntryMetadata(t *testing.T) {
+	created := time.Date(2024, 3, 5, 9, 30, 0, 0, time.UTC)
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockDB.On("QueryRecent", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.QueryRecentResponse{
+			Entries: []*pb.DataBaseSchema{
+				{Summary: "**SUBJECT: Budget review**\nsummary", CreatedAt: timestamppb.New(created)},
+			},
+		}, nil)
+
+	header := "[" + created.Local().Format(entryHeaderDateLayout) + "] Budget review:"
+	mockLLM := new(mocks.MockLLMSummaryServiceClient)
+	mockLLM.On("Summarize", mock.Anything, mock.MatchedBy(func(req *pb.LLMSummaryRequest) bool {
+		return strings.Contains(req.Text, header+"\n**SUBJECT: Budget review**")
+	}), mock.Anything).
+		Return(&pb.LLMSummaryResponse{Summary: "digest"}, nil)
+
+	w, router := setupSummaryTest(mockDB, mockLLM)
+	req, _ := http.NewRequest(http.MethodGet, "/api/summary", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockLLM.AssertExpectations(t)
+}
+
+func TestHandleSummary_UsesConfiguredSplitter(t *testing.T) {
+	useEntrySplitter(t, "~~~")
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockDB.On("QueryRecent", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.QueryRecentResponse{
+			Entries: []*pb.DataBaseSchema{{Summary: "one"}, {Summary: "two"}},
+		}, nil)
+
+	mockLLM := new(mocks.MockLLMSummaryServiceClient)
+	mockLLM.On("Summarize", mock.Anything, mock.MatchedBy(func(req *pb.LLMSummaryRequest) bool {
+		return req.Text == "~~~\none\n~~~\ntwo\n~~~\n"
+	}), mock.Anything).
+		Return(&pb.LLMSummaryResponse{Summary: "digest"}, nil)
+
+	w, router := setupSummaryTest(mockDB, mockLLM)
+	req, _ := http.NewRequest(http.MethodGet, "/api/summary", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockLLM.AssertExpectations(t)
+}
+
 func TestHandleSummary_VerifiesPromptAndContent(t *testing.T) {
 	mockDB := new(mocks.MockDataBaseServiceClient)
 	mockDB.On("QueryRecent", mock.Anything, mock.Anything, mock.Anything).
@@ -176,3 +282,181 @@ func TestHandleSummary_VerifiesPromptAndContent(t *testing.T) {
 
 	mockLLM.AssertExpectations(t)
 }
+
+func TestHandleSummary_PlainFormat(t *testing.T) {
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockDB.On("QueryRecent", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.QueryRecentResponse{Entries: []*pb.DataBaseSchema{{Summary: "a"}}}, nil)
+	mockLLM := new(mocks.MockLLMSummaryServiceClient)
+	mockLLM.On("Summarize", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.LLMSummaryResponse{Summary: "## Today\n* **Ship** the [release](https://example.com/r)"}, nil)
+
+	w, router := setupSummaryTest(mockDB, mockLLM)
+	req, _ := http.NewRequest(http.MethodGet, "/api/summary?format=plain", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "Today\n- Ship the release (https://example.com/r)", body["summary"])
+}
+
+func TestHandleSummary_InvalidFormat(t *testing.T) {
+	w, router := setupSummaryTest(new(mocks.MockDataBaseServiceClient), nil)
+	req, _ := http.NewRequest(http.MethodGet, "/api/summary?format=html", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid format parameter")
+}
+
+func TestHandleSummary_LLMStatusMapping(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{
+			"token limit", status.Error(codes.ResourceExhausted, "daily token limit exceeded"),
+			http.StatusTooManyRequests,
+		},
+		{"invalid argument", status.Error(codes.InvalidArgument, "unsupported model"), http.StatusBadRequest},
+		{"generation failure", status.Error(codes.Internal, "all models failed"), http.StatusInternalServerError},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDB := new(mocks.MockDataBaseServiceClient)
+			mockDB.On("QueryRecent", mock.Anything, mock.Anything, mock.Anything).
+				Return(&pb.QueryRecentResponse{
+					Entries: []*pb.DataBaseSchema{{Summary: "some task"}},
+				}, nil)
+			mockLLM := new(mocks.MockLLMSummaryServiceClient)
+			mockLLM.On("Summarize", mock.Anything, mock.Anything, mock.Anything).Return(nil, tc.err)
+
+			w, router := setupSummaryTest(mockDB, mockLLM)
+			req, _ := http.NewRequest(http.MethodGet, "/api/summary", nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantCode, w.Code)
+			assert.Contains(t, w.Body.String(), "error in summarizing email")
+		})
+	}
+}
+
+func TestHandleSummary_EntryLimit(t *testing.T) {
+	base := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockDB.On("QueryRecent", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.QueryRecentResponse{
+			Entries: []*pb.DataBaseSchema{
+				{Summary: "**SUBJECT: old task**", CreatedAt: timestamppb.New(base)},
+				{Summary: "**SUBJECT: new task**", CreatedAt: timestamppb.New(base.Add(time.Hour))},
+			},
+		}, nil)
+	mockLLM := new(mocks.MockLLMSummaryServiceClient)
+	mockLLM.On("Summarize", mock.Anything, mock.MatchedBy(func(req *pb.LLMSummaryRequest) bool {
+		return strings.Contains(req.Text, "new task") && !strings.Contains(req.Text, "old task")
+	}), mock.Anything).Return(&pb.LLMSummaryResponse{Summary: "digest"}, nil)
+
+	w, router := setupSummaryTest(mockDB, mockLLM)
+	req, _ := http.NewRequest(http.MethodGet, "/api/summary?limit=1", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.EqualValues(t, 1, body["task_count"])
+	assert.EqualValues(t, 1, body["entry_limit"])
+	mockLLM.AssertExpectations(t)
+}
+
+func TestHandleSummary_CustomCategories(t *testing.T) {
+	original := summaryCategories
+	summaryCategories = []string{"Urgent", "Waiting", "Later"}
+	t.Cleanup(func() { summaryCategories = original })
+
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockDB.On("QueryRecent", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.QueryRecentResponse{Entries: []*pb.DataBaseSchema{{Summary: "task"}}}, nil)
+	mockLLM := new(mocks.MockLLMSummaryServiceClient)
+	mockLLM.On("Summarize", mock.Anything, mock.MatchedBy(func(req *pb.LLMSummaryRequest) bool {
+		return strings.Contains(req.Prompt, `three categories: "Urgent", "Waiting", "Later"`) &&
+			!strings.Contains(req.Prompt, "Low Priority")
+	}), mock.Anything).Return(&pb.LLMSummaryResponse{Summary: "digest"}, nil)
+
+	w, router := setupSummaryTest(mockDB, mockLLM)
+	req, _ := http.NewRequest(http.MethodGet, "/api/summary", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockLLM.AssertExpectations(t)
+}
+
+// usePromptWrap sets promptPrefix and promptSuffix for the duration of a test.
+func usePromptWrap(t *testing.T, prefix, suffix string) {
+	t.Helper()
+	originalPrefix, originalSuffix := promptPrefix, promptSuffix
+	promptPrefix, promptSuffix = prefix, suffix
+	t.Cleanup(func() { promptPrefix, promptSuffix = originalPrefix, originalSuffix })
+}
+
+func TestHandleSummary_PromptPrefixAndSuffix(t *testing.T) {
+	usePromptWrap(t, "Be brief.", "Always mention deadlines.")
+
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockDB.On("QueryRecent", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.QueryRecentResponse{Entries: []*pb.DataBaseSchema{{Summary: "task"}}}, nil)
+	mockLLM := new(mocks.MockLLMSummaryServiceClient)
+	mockLLM.On("Summarize", mock.Anything, mock.MatchedBy(func(req *pb.LLMSummaryRequest) bool {
+		return req.Prompt == "Be brief.\n\n"+utils.DefaultPromptToSummaryEmailRange+"\n\nAlways mention deadlines."
+	}), mock.Anything).Return(&pb.LLMSummaryResponse{Summary: "digest"}, nil)
+
+	w, router := setupSummaryTest(mockDB, mockLLM)
+	req, _ := http.NewRequest(http.MethodGet, "/api/summary", nil)
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockLLM.AssertExpectations(t)
+}
+
+func TestHandleSummary_EntriesJSON(t *testing.T) {
+	useEntriesJSON(t)
+
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockDB.On("QueryRecent", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.QueryRecentResponse{Entries: []*pb.DataBaseSchema{
+			{Summary: "**SUBJECT: Invoice**\npay it"},
+			{Summary: "**SUBJECT: Lunch**\nnoon"},
+		}}, nil)
+	var sent *pb.LLMSummaryRequest
+	mockLLM := new(mocks.MockLLMSummaryServiceClient)
+	mockLLM.On("Summarize", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { sent = args.Get(1).(*pb.LLMSummaryRequest) }).
+		Return(&pb.LLMSummaryResponse{Summary: "digest"}, nil)
+
+	w, router := setupSummaryTest(mockDB, mockLLM)
+	req, _ := http.NewRequest(http.MethodGet, "/api/summary", nil)
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotNil(t, sent)
+	assert.True(t, strings.HasSuffix(sent.Prompt, "\n"+utils.PromptEntriesJSON))
+	var payload []map[string]any
+	require.NoError(t, json.Unmarshal([]byte(sent.Text), &payload))
+	require.Len(t, payload, 2)
+	assert.Equal(t, map[string]any{
+		"index": float64(1), "subject": "Invoice", "summary": "**SUBJECT: Invoice**\npay it",
+	}, payload[0])
+	assert.Equal(t, "Lunch", payload[1]["subject"])
+}
+
+func TestHandleSummary_InvalidEntryLimit(t *testing.T) {
+	for _, limit := range []string{"0", "-1", "abc", "201"} {
+		w, router := setupSummaryTest(new(mocks.MockDataBaseServiceClient), nil)
+		req, _ := http.NewRequest(http.MethodGet, "/api/summary?limit="+limit, nil)
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, limit)
+		assert.Contains(t, w.Body.String(), "invalid limit parameter")
+	}
+}
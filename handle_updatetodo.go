@@ -4,16 +4,21 @@ import (
 	"bytes"
 	"crypto/sha256"
 	"fmt"
+	"html"
 	"html/template"
 	"io"
 	"net/http"
+	"net/mail"
 	"regexp"
 	"strings"
+	"time"
 
 	_ "embed"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ziyixi/todofy/utils"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
 
 	pb "github.com/ziyixi/protos/go/todofy"
 )
@@ -21,42 +26,109 @@ import (
 //go:embed templates/todoDescription.tmpl
 var descriptionTmpl string
 
+// descriptionFuncs lets the description template emit markdown link parts verbatim:
+// html/template would otherwise turn the & in presigned attachment URLs into &amp;.
+var descriptionFuncs = template.FuncMap{
+	"unescaped": func(s string) template.HTML { return template.HTML(s) },
+}
+
+// systemEmailSender is the address todofy's own mails are sent from (--system-email-sender).
+// When empty, system mail is recognized by the subject prefix only.
+var systemEmailSender string
+
+// allowedSenderDomains restricts which sender domains may create tasks (--allowed-sender-domains).
+// Empty allows every sender.
+var allowedSenderDomains []string
+
+// emailParseOptions controls the HTML-to-markdown conversion of inbound mail
+// (--strip-email-images, --flatten-email-tables); ?keep_urls is applied per request.
+var emailParseOptions utils.ParseOptions
+
+// annotateTaskModel appends the model that wrote the summary to each task body (--annotate-task-model).
+// Only the task gets the note; the stored summary stays as rendered.
+var annotateTaskModel bool
+
+// updateTodoSteps records which stages of HandleUpdateTodo completed, so a failed
+// request tells the caller what still needs manual recovery.
+type updateTodoSteps struct {
+	Summarized  bool
+	TodoCreated bool
+	DBWritten   bool
+}
+
+// response adds the per-step flags to fields.
+func (s updateTodoSteps) response(fields gin.H) gin.H {
+	fields["summarized"] = s.Summarized
+	fields["todo_created"] = s.TodoCreated
+	fields["db_written"] = s.DBWritten
+	return fields
+}
+
 // HandleUpdateTodo converts inbound email payloads into summarized Todoist tasks.
+// Links are stripped from the email to save tokens unless ?keep_urls=true is set.
+// Failed requests are dead-lettered to --dead-letter-dir when it is set. Backend call durations
+// are logged and returned in the Server-Timing header.
 func HandleUpdateTodo(c *gin.Context) {
-	clients := c.MustGet(utils.KeyGRPCClients).(ClientProvider)
+	clients, err := GetClients(c)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
 	// get the post data
 	jsonRaw, err := io.ReadAll(c.Request.Body)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error in reading json body": err.Error()})
 		return
 	}
+	keepURLs, err := parseBoolQuery(c, "keep_urls", false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid keep_urls parameter: must be a boolean"})
+		return
+	}
 	jsonString := string(jsonRaw)
-	emailContent := utils.ParseCloudmailin(jsonString)
+	parseOptions := emailParseOptions
+	parseOptions.KeepURLs = keepURLs
+	emailContent := utils.ParseCloudmailinWithOptions(jsonString, parseOptions)
 	if len(emailContent.From) == 0 || len(emailContent.To) == 0 ||
 		(len(emailContent.Subject) == 0 && len(emailContent.Content) == 0) {
 		c.JSON(http.StatusBadRequest, gin.H{"error in parsing json body": "from/to/subject/content is empty"})
 		return
 	}
-	if strings.HasPrefix(emailContent.Subject, utils.SystemAutomaticallyEmailPrefix) {
+	if isSystemEmail(emailContent) {
 		c.JSON(http.StatusOK, gin.H{"accept request": "this is a system automatically email, and will not be processed"})
 		return
 	}
+	if !isAllowedSender(emailContent.From) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "sender domain is not allowed"})
+		return
+	}
+
+	timings := newStepTimings(c)
+	defer func() {
+		fields := timings.logFields()
+		fields["request_bytes"] = len(jsonRaw)
+		fields["response_bytes"] = c.Writer.Size()
+		log.WithFields(fields).Infof("update_todo finished with status %d", c.Writer.Status())
+	}()
 
-	// Compute hash_id from prompt + email content for dedup
-	hashInput := utils.DefaultPromptToSummaryEmail + emailContent.Content
-	hashID := fmt.Sprintf("%x", sha256.Sum256([]byte(hashInput)))
+	// Compute hash_id from exactly what the LLM is asked, so a prompt or header change misses the cache
+	llmReq := emailSummaryRequest(emailContent)
+	hashID := summaryCacheKey(llmReq)
 
 	// Check if we already have a cached result for this hash
 	databaseClient := clients.GetClient("database").(pb.DataBaseServiceClient)
 	checkReq := &pb.CheckExistRequest{
-		Type:   pb.DatabaseType_DATABASE_TYPE_SQLITE,
+		Type:   databaseType,
 		HashId: hashID,
 	}
+	start := time.Now()
 	checkResp, err := databaseClient.CheckExist(c, checkReq)
+	timings.record("check_cache", start)
 	if err != nil {
 		log.Warningf("CheckExist failed (proceeding without cache): %v", err)
 	}
 
+	var steps updateTodoSteps
 	var summaryResp *pb.LLMSummaryResponse
 	var summaryReq *pb.LLMSummaryRequest
 	todoContent := ""
@@ -74,44 +146,55 @@ func HandleUpdateTodo(c *gin.Context) {
 			Text:        checkResp.Entry.Text,
 		}
 		todoContent = checkResp.Entry.Summary
+		steps.Summarized = true
 	} else {
 		// Cache miss — call LLM
-		summaryReq = &pb.LLMSummaryRequest{
-			ModelFamily: pb.ModelFamily_MODEL_FAMILY_GEMINI,
-			Prompt:      utils.DefaultPromptToSummaryEmail,
-			Text:        emailContent.Content,
-		}
+		summaryReq = llmReq
 		llmClient := clients.GetClient("llm").(pb.LLMSummaryServiceClient)
-		summaryResp, err = llmClient.Summarize(c, summaryReq)
+		start = time.Now()
+		var trailer metadata.MD
+		summaryResp, err = llmClient.Summarize(c, summaryReq, grpc.Trailer(&trailer))
+		logLLMFallback("update_todo", trailer)
+		timings.record("summarize", start)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error in summarizing email": err.Error()})
+			failUpdateTodo(
+				c, jsonRaw, llmErrorHTTPStatus(err),
+				steps.response(gin.H{"error in summarizing email": err.Error()}),
+			)
 			return
 		}
+		steps.Summarized = true
 
 		// Remove all # started tags in summary, use regex to match [space]#[arbitrary less than 10 characters]
 		regex := regexp.MustCompile(`\s#[a-zA-Z0-9]{1,10}\s`)
 		summaryResp.Summary = regex.ReplaceAllString(summaryResp.Summary, "<removed tag>")
 		emailContentWithSummary := utils.MailInfo{
-			From:    emailContent.From,
-			To:      emailContent.To,
-			Date:    emailContent.Date,
-			Subject: emailContent.Subject,
-			Content: summaryResp.Summary, // use the summary as the content
+			From:        emailContent.From,
+			To:          emailContent.To,
+			Date:        emailContent.Date,
+			Subject:     emailContent.Subject,
+			Content:     summaryResp.Summary, // use the summary as the content
+			MessageID:   emailContent.MessageID,
+			Attachments: emailContent.Attachments,
 		}
 
 		// prepare task description, load template
-		tmpl, err := template.New("todoDescription").Parse(descriptionTmpl)
+		tmpl, err := template.New("todoDescription").Funcs(descriptionFuncs).Parse(descriptionTmpl)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error in parsing template": err.Error()})
+			failUpdateTodo(
+				c, jsonRaw, http.StatusInternalServerError,
+				steps.response(gin.H{"error in parsing template": err.Error()}),
+			)
 			return
 		}
-		var buf bytes.Buffer
-		err = tmpl.Execute(&buf, emailContentWithSummary)
+		todoContent, err = renderTodoDescription(tmpl, emailContentWithSummary)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error in executing template": err.Error()})
+			failUpdateTodo(
+				c, jsonRaw, http.StatusInternalServerError,
+				steps.response(gin.H{"error in executing template": err.Error()}),
+			)
 			return
 		}
-		todoContent = buf.String()
 	}
 
 	// create a todo item
@@ -119,19 +202,25 @@ func HandleUpdateTodo(c *gin.Context) {
 		App:     pb.TodoApp_TODO_APP_TODOIST,
 		Method:  pb.PopullateTodoMethod_POPULLATE_TODO_METHOD_TODOIST,
 		Subject: emailContent.Subject,
-		Body:    todoContent,
+		Body:    taskBody(todoContent, summaryResp.Model),
 		From:    emailContent.From,
 	}
 	todoClient := clients.GetClient("todo").(pb.TodoServiceClient)
+	start = time.Now()
 	_, err = todoClient.PopulateTodo(c, todoReq)
+	timings.record("todo_create", start)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error in creating todo": err.Error()})
+		failUpdateTodo(
+			c, jsonRaw, http.StatusInternalServerError,
+			steps.response(gin.H{"error in creating todo": err.Error()}),
+		)
 		return
 	}
+	steps.TodoCreated = true
 
 	// Write this session to database
 	databaseReq := &pb.WriteRequest{
-		Type: pb.DatabaseType_DATABASE_TYPE_SQLITE,
+		Type: databaseType,
 		Schema: &pb.DataBaseSchema{
 			ModelFamily: summaryReq.ModelFamily,
 			Model:       summaryResp.Model,
@@ -142,10 +231,120 @@ func HandleUpdateTodo(c *gin.Context) {
 			HashId:      hashID,
 		},
 	}
+	start = time.Now()
 	_, err = databaseClient.Write(c, databaseReq)
+	timings.record("db_write", start)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error in writing to database": err.Error()})
+		failUpdateTodo(
+			c, jsonRaw, http.StatusInternalServerError,
+			steps.response(gin.H{"error in writing to database": err.Error()}),
+		)
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{"message": "todo created successfully"})
+	steps.DBWritten = true
+	c.JSON(http.StatusOK, steps.response(gin.H{
+		"message": "todo created successfully",
+		// From the rendered body, so a cache hit echoes the same summary as the first request
+		"summary": descriptionSummary(todoContent),
+		"model":   utils.ModelName(summaryResp.Model),
+	}))
+}
+
+// isSystemEmail reports whether an inbound email was generated by todofy itself: it comes from
+// the configured sender address or its subject starts with the system prefix. A user forwarding
+// a digest ("Fwd: [Todofy System] ...") is still processed.
+func isSystemEmail(email utils.MailInfo) bool {
+	if systemEmailSender != "" && strings.EqualFold(emailAddress(email.From), strings.TrimSpace(systemEmailSender)) {
+		return true
+	}
+	return strings.HasPrefix(email.Subject, utils.SystemAutomaticallyEmailPrefix)
+}
+
+// isAllowedSender reports whether from's domain is in allowedSenderDomains.
+func isAllowedSender(from string) bool {
+	if len(allowedSenderDomains) == 0 {
+		return true
+	}
+	addr := emailAddress(from)
+	at := strings.LastIndex(addr, "@")
+	if at < 0 {
+		return false
+	}
+	domain := addr[at+1:]
+	for _, allowed := range allowedSenderDomains {
+		if strings.EqualFold(domain, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSenderDomains splits a comma-separated domain list, dropping blanks and a leading "@".
+func parseSenderDomains(raw string) []string {
+	var domains []string
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimPrefix(strings.TrimSpace(part), "@")
+		if part != "" {
+			domains = append(domains, part)
+		}
+	}
+	return domains
+}
+
+// emailAddress extracts the bare address from a header value like "Name <addr@example.com>".
+func emailAddress(header string) string {
+	if addr, err := mail.ParseAddress(header); err == nil {
+		return addr.Address
+	}
+	return strings.TrimSpace(header)
+}
+
+// renderTodoDescription executes the task description template for info.
+// The message ID and attachments sections are omitted when those fields are empty.
+func renderTodoDescription(tmpl *template.Template, info utils.MailInfo) (string, error) {
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, info); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// descriptionSeparator is the line templates/todoDescription.tmpl puts before the summary
+// and before the attachments section.
+const descriptionSeparator = "========================"
+
+// descriptionSummary extracts the summary from a task description rendered by
+// renderTodoDescription, undoing html/template's escaping. A body without the header
+// block is returned whole.
+func descriptionSummary(description string) string {
+	summary := description
+	if _, rest, ok := strings.Cut(description, "\n"+descriptionSeparator+"\n"); ok {
+		summary, _, _ = strings.Cut(rest, "\n\n"+descriptionSeparator+"\n**ATTACHMENTS:**")
+	}
+	return html.UnescapeString(summary)
+}
+
+// taskBody returns the task description, followed by a "summarized by" note naming model
+// when --annotate-task-model is set and the model is known.
+func taskBody(content string, model pb.Model) string {
+	name := utils.ModelName(model)
+	if !annotateTaskModel || name == "" {
+		return content
+	}
+	return strings.TrimRight(content, "\n") + "\n\n— summarized by " + name
+}
+
+// emailSummaryRequest builds the LLM request that summarizes one email.
+func emailSummaryRequest(info utils.MailInfo) *pb.LLMSummaryRequest {
+	return &pb.LLMSummaryRequest{
+		ModelFamily: pb.ModelFamily_MODEL_FAMILY_GEMINI,
+		Prompt:      utils.WrapPrompt(utils.DefaultPromptToSummaryEmail, promptPrefix, promptSuffix),
+		Text:        utils.MailInputText(info),
+	}
+}
+
+// summaryCacheKey is the hash_id an email summary is cached under: the SHA-256 of the
+// prompt and input text sent to the LLM.
+func summaryCacheKey(req *pb.LLMSummaryRequest) string {
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(req.Prompt+req.Text)))
 }
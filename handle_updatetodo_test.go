@@ -2,18 +2,25 @@ package main
 
 import (
 	"crypto/sha256"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"html/template"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"github.com/ziyixi/todofy/testutils/mocks"
 	"github.com/ziyixi/todofy/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	pb "github.com/ziyixi/protos/go/todofy"
 )
@@ -37,7 +44,7 @@ func setupUpdateTodoTest(
 
 	router := gin.New()
 	router.Use(func(c *gin.Context) {
-		c.Set(utils.KeyGRPCClients, clients)
+		SetClients(c, clients)
 		c.Next()
 	})
 	router.POST("/api/updatetodo", HandleUpdateTodo)
@@ -55,11 +62,21 @@ func validEmailJSON(from, to, subject, content string) string {
 	}`, from, to, subject, "<p>"+content+"</p>", content)
 }
 
-// computeExpectedHash returns the SHA-256 hex hash of DefaultPromptToSummaryEmail + content,
-// matching the logic in HandleUpdateTodo.
-func computeExpectedHash(content string) string {
-	hashInput := utils.DefaultPromptToSummaryEmail + content
-	return fmt.Sprintf("%x", sha256.Sum256([]byte(hashInput)))
+// computeExpectedHash returns the SHA-256 hex hash of the prompt and input text that
+// HandleUpdateTodo sends to the LLM for the email body.
+func computeExpectedHash(body string) string {
+	req := emailSummaryRequest(utils.ParseCloudmailin(body))
+	return fmt.Sprintf("%x", sha256.Sum256([]byte(req.Prompt+req.Text)))
+}
+
+// assertUpdateTodoSteps checks the per-step flags in a HandleUpdateTodo response.
+func assertUpdateTodoSteps(t *testing.T, w *httptest.ResponseRecorder, summarized, todoCreated, dbWritten bool) {
+	t.Helper()
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, summarized, body["summarized"], "summarized")
+	assert.Equal(t, todoCreated, body["todo_created"], "todo_created")
+	assert.Equal(t, dbWritten, body["db_written"], "db_written")
 }
 
 func TestHandleUpdateTodo_SuccessCacheMiss(t *testing.T) {
@@ -94,11 +111,155 @@ func TestHandleUpdateTodo_SuccessCacheMiss(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "todo created successfully")
+	assertUpdateTodoSteps(t, w, true, true, true)
 	mockDB.AssertExpectations(t)
 	mockLLM.AssertExpectations(t)
 	mockTodo.AssertExpectations(t)
 }
 
+func TestHandleUpdateTodo_ResponseEchoesSummary(t *testing.T) {
+	const summary = "Reply to Tom & Ana by Friday"
+	body := validEmailJSON("sender@example.com", "me@test.com", "Lease", "Please reply by Friday")
+
+	tmpl, err := template.New("todoDescription").Funcs(descriptionFuncs).Parse(descriptionTmpl)
+	require.NoError(t, err)
+	info := utils.ParseCloudmailin(body)
+	info.Content = summary
+	info.Attachments = []utils.Attachment{{FileName: "lease.pdf", URL: "https://files.example.com/lease.pdf"}}
+	cachedBody, err := renderTodoDescription(tmpl, info)
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		cached *pb.DataBaseSchema
+	}{
+		{name: "cache miss"},
+		{name: "cache hit", cached: &pb.DataBaseSchema{Summary: cachedBody, Model: pb.Model_MODEL_GEMINI_2_5_FLASH}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := new(mocks.MockDataBaseServiceClient)
+			mockLLM := new(mocks.MockLLMSummaryServiceClient)
+			mockTodo := new(mocks.MockTodoServiceClient)
+
+			mockDB.On("CheckExist", mock.Anything, mock.Anything, mock.Anything).
+				Return(&pb.CheckExistResponse{Entry: tt.cached}, nil)
+			mockLLM.On("Summarize", mock.Anything, mock.Anything, mock.Anything).
+				Return(&pb.LLMSummaryResponse{Summary: summary, Model: pb.Model_MODEL_GEMINI_2_5_FLASH}, nil)
+			mockTodo.On("PopulateTodo", mock.Anything, mock.Anything, mock.Anything).
+				Return(&pb.TodoResponse{}, nil)
+			mockDB.On("Write", mock.Anything, mock.Anything, mock.Anything).
+				Return(&pb.WriteResponse{}, nil)
+
+			w, router := setupUpdateTodoTest(mockDB, mockLLM, mockTodo)
+			req, _ := http.NewRequest(http.MethodPost, "/api/updatetodo", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, http.StatusOK, w.Code)
+			var resp map[string]any
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, summary, resp["summary"])
+			assert.Equal(t, "gemini-2.5-flash", resp["model"])
+		})
+	}
+}
+
+func TestDescriptionSummary(t *testing.T) {
+	assert.Equal(t, "plain & simple", descriptionSummary("plain &amp; simple"))
+	assert.Equal(t, "line one\nline two",
+		descriptionSummary("**FROM: a**\n\n========================\nline one\nline two"))
+}
+
+func TestHandleUpdateTodo_StepTimings(t *testing.T) {
+	hook := logtest.NewLocal(log)
+	t.Cleanup(func() { log.ReplaceHooks(make(logrus.LevelHooks)) })
+
+	newMocks := func(todoErr error) (
+		*mocks.MockDataBaseServiceClient, *mocks.MockLLMSummaryServiceClient, *mocks.MockTodoServiceClient,
+	) {
+		mockDB := new(mocks.MockDataBaseServiceClient)
+		mockLLM := new(mocks.MockLLMSummaryServiceClient)
+		mockTodo := new(mocks.MockTodoServiceClient)
+		mockDB.On("CheckExist", mock.Anything, mock.Anything, mock.Anything).
+			Return(&pb.CheckExistResponse{}, nil)
+		mockLLM.On("Summarize", mock.Anything, mock.Anything, mock.Anything).
+			Return(&pb.LLMSummaryResponse{Summary: "summary"}, nil)
+		if todoErr != nil {
+			mockTodo.On("PopulateTodo", mock.Anything, mock.Anything, mock.Anything).Return(nil, todoErr)
+		} else {
+			mockTodo.On("PopulateTodo", mock.Anything, mock.Anything, mock.Anything).
+				Return(&pb.TodoResponse{}, nil)
+			mockDB.On("Write", mock.Anything, mock.Anything, mock.Anything).Return(&pb.WriteResponse{}, nil)
+		}
+		return mockDB, mockLLM, mockTodo
+	}
+	post := func(todoErr error) *httptest.ResponseRecorder {
+		hook.Reset()
+		w, router := setupUpdateTodoTest(newMocks(todoErr))
+		body := validEmailJSON("sender@example.com", "me@test.com", "Test Subject", "Test content")
+		req, _ := http.NewRequest(http.MethodPost, "/api/updatetodo", strings.NewReader(body))
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("success reports every step", func(t *testing.T) {
+		w := post(nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Regexp(t,
+			`^check_cache;dur=[\d.]+, summarize;dur=[\d.]+, todo_create;dur=[\d.]+, db_write;dur=[\d.]+$`,
+			w.Header().Get(serverTimingHeader))
+
+		entry := hook.LastEntry()
+		require.NotNil(t, entry)
+		assert.Contains(t, entry.Message, "update_todo finished with status 200")
+		for _, field := range []string{"check_cache_ms", "summarize_ms", "todo_create_ms", "db_write_ms"} {
+			assert.Contains(t, entry.Data, field)
+		}
+		assert.Equal(t, w.Body.Len(), entry.Data["response_bytes"])
+		assert.Greater(t, entry.Data["request_bytes"], 0)
+	})
+
+	t.Run("failure reports the steps that ran", func(t *testing.T) {
+		w := post(errors.New("todoist down"))
+		require.Equal(t, http.StatusInternalServerError, w.Code)
+		timing := w.Header().Get(serverTimingHeader)
+		assert.Contains(t, timing, "todo_create;dur=")
+		assert.NotContains(t, timing, "db_write")
+
+		entry := hook.LastEntry()
+		require.NotNil(t, entry)
+		assert.Contains(t, entry.Message, "status 500")
+		assert.NotContains(t, entry.Data, "db_write_ms")
+	})
+}
+
+func TestHandleUpdateTodo_UsesConfiguredDatabaseType(t *testing.T) {
+	useDatabaseType(t, testDatabaseType)
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockTodo := new(mocks.MockTodoServiceClient)
+
+	mockDB.On("CheckExist", mock.Anything, mock.MatchedBy(func(req *pb.CheckExistRequest) bool {
+		return req.Type == testDatabaseType
+	}), mock.Anything).Return(&pb.CheckExistResponse{
+		Entry: &pb.DataBaseSchema{Summary: "Cached summary from DB"},
+	}, nil)
+	mockTodo.On("PopulateTodo", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.TodoResponse{}, nil)
+	mockDB.On("Write", mock.Anything, mock.MatchedBy(func(req *pb.WriteRequest) bool {
+		return req.Type == testDatabaseType
+	}), mock.Anything).Return(&pb.WriteResponse{}, nil)
+
+	w, router := setupUpdateTodoTest(mockDB, nil, mockTodo)
+	body := validEmailJSON("sender@example.com", "me@test.com", "Test Subject", "Test content")
+	req, _ := http.NewRequest(http.MethodPost, "/api/updatetodo", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockDB.AssertExpectations(t)
+}
+
 func TestHandleUpdateTodo_SuccessCacheHit(t *testing.T) {
 	mockDB := new(mocks.MockDataBaseServiceClient)
 	mockTodo := new(mocks.MockTodoServiceClient)
@@ -137,6 +298,7 @@ func TestHandleUpdateTodo_SuccessCacheHit(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "todo created successfully")
+	assertUpdateTodoSteps(t, w, true, true, true)
 	mockDB.AssertExpectations(t)
 	mockTodo.AssertExpectations(t)
 }
@@ -183,6 +345,93 @@ func TestHandleUpdateTodo_SystemEmailPrefix(t *testing.T) {
 	assert.Contains(t, w.Body.String(), "system automatically email")
 }
 
+func useSystemEmailSender(t *testing.T, sender string) {
+	t.Helper()
+	original := systemEmailSender
+	systemEmailSender = sender
+	t.Cleanup(func() {
+		systemEmailSender = original
+	})
+}
+
+func TestHandleUpdateTodo_SystemEmailSender(t *testing.T) {
+	useSystemEmailSender(t, "digest@todofy.example")
+	mockDB := new(mocks.MockDataBaseServiceClient)
+
+	w, router := setupUpdateTodoTest(mockDB, nil, nil)
+	body := validEmailJSON("Todofy <Digest@todofy.example>", "me@test.com", "Your morning overview", "Some content")
+	req, _ := http.NewRequest(http.MethodPost, "/api/updatetodo", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "system automatically email")
+	mockDB.AssertNotCalled(t, "CheckExist", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestIsSystemEmail(t *testing.T) {
+	digestSubject := utils.SystemAutomaticallyEmailPrefix + " daily summary"
+
+	t.Run("prefix fallback without configured sender", func(t *testing.T) {
+		useSystemEmailSender(t, "")
+		assert.True(t, isSystemEmail(utils.MailInfo{From: "anyone@example.com", Subject: digestSubject}))
+		assert.False(t, isSystemEmail(utils.MailInfo{From: "anyone@example.com", Subject: "hello"}))
+	})
+
+	t.Run("configured sender matches any subject", func(t *testing.T) {
+		useSystemEmailSender(t, "digest@todofy.example")
+		assert.True(t, isSystemEmail(utils.MailInfo{From: "digest@todofy.example", Subject: "anything"}))
+		// A user forwarding a digest comes from their own address and no longer starts with the prefix
+		assert.False(t, isSystemEmail(utils.MailInfo{From: "Me <me@example.com>", Subject: "Fwd: " + digestSubject}))
+	})
+
+	t.Run("prefix still matches when a different sender is configured", func(t *testing.T) {
+		useSystemEmailSender(t, "digest@todofy.example")
+		assert.True(t, isSystemEmail(utils.MailInfo{From: "relay@example.com", Subject: digestSubject}))
+		assert.False(t, isSystemEmail(utils.MailInfo{From: "relay@example.com", Subject: "hello"}))
+	})
+}
+
+func useAllowedSenderDomains(t *testing.T, raw string) {
+	t.Helper()
+	original := allowedSenderDomains
+	allowedSenderDomains = parseSenderDomains(raw)
+	t.Cleanup(func() {
+		allowedSenderDomains = original
+	})
+}
+
+func TestHandleUpdateTodo_DisallowedSenderDomain(t *testing.T) {
+	useAllowedSenderDomains(t, "example.com")
+	mockDB := new(mocks.MockDataBaseServiceClient)
+
+	w, router := setupUpdateTodoTest(mockDB, nil, nil)
+	body := validEmailJSON("Spammer <spam@elsewhere.net>", "me@test.com", "Buy now", "Some content")
+	req, _ := http.NewRequest(http.MethodPost, "/api/updatetodo", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "sender domain is not allowed")
+	mockDB.AssertNotCalled(t, "CheckExist", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestIsAllowedSender(t *testing.T) {
+	t.Run("empty list allows all", func(t *testing.T) {
+		useAllowedSenderDomains(t, "")
+		assert.True(t, isAllowedSender("anyone@anywhere.org"))
+	})
+
+	t.Run("matches configured domains case-insensitively", func(t *testing.T) {
+		useAllowedSenderDomains(t, " example.com, @Work.IO ,")
+		assert.Equal(t, []string{"example.com", "Work.IO"}, allowedSenderDomains)
+		assert.True(t, isAllowedSender("Alice <alice@EXAMPLE.com>"))
+		assert.True(t, isAllowedSender("bob@work.io"))
+		assert.False(t, isAllowedSender("eve@example.com.evil.net"))
+		assert.False(t, isAllowedSender("sender unknown"))
+	})
+}
+
 func TestHandleUpdateTodo_CheckExistError(t *testing.T) {
 	mockDB := new(mocks.MockDataBaseServiceClient)
 	mockLLM := new(mocks.MockLLMSummaryServiceClient)
@@ -213,6 +462,7 @@ func TestHandleUpdateTodo_CheckExistError(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 	assert.Contains(t, w.Body.String(), "todo created successfully")
+	assertUpdateTodoSteps(t, w, true, true, true)
 	mockLLM.AssertExpectations(t)
 	mockDB.AssertExpectations(t)
 }
@@ -235,6 +485,7 @@ func TestHandleUpdateTodo_LLMError(t *testing.T) {
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 	assert.Contains(t, w.Body.String(), "llm quota exceeded")
+	assertUpdateTodoSteps(t, w, false, false, false)
 	mockDB.AssertExpectations(t)
 	mockLLM.AssertExpectations(t)
 }
@@ -264,6 +515,7 @@ func TestHandleUpdateTodo_TodoError(t *testing.T) {
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 	assert.Contains(t, w.Body.String(), "todoist API down")
+	assertUpdateTodoSteps(t, w, true, false, false)
 	mockDB.AssertExpectations(t)
 	mockLLM.AssertExpectations(t)
 	mockTodo.AssertExpectations(t)
@@ -297,6 +549,7 @@ func TestHandleUpdateTodo_DBWriteError(t *testing.T) {
 
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 	assert.Contains(t, w.Body.String(), "disk full")
+	assertUpdateTodoSteps(t, w, true, true, false)
 	mockDB.AssertExpectations(t)
 	mockLLM.AssertExpectations(t)
 	mockTodo.AssertExpectations(t)
@@ -356,8 +609,7 @@ func TestHandleUpdateTodo_HashConsistency(t *testing.T) {
 		"plain": %q
 	}`, emailContent)
 	// ParseCloudmailin falls back to plain when html is empty
-	parsed := utils.ParseCloudmailin(emailBody)
-	expectedHash := computeExpectedHash(parsed.Content)
+	expectedHash := computeExpectedHash(emailBody)
 
 	// Verify CheckExist receives the expected hash
 	mockDB.On("CheckExist", mock.Anything,
@@ -390,3 +642,322 @@ func TestHandleUpdateTodo_HashConsistency(t *testing.T) {
 	assert.Equal(t, http.StatusOK, w.Code)
 	mockDB.AssertExpectations(t)
 }
+
+func TestHandleUpdateTodo_CacheKeyCoversPromptAndHeaders(t *testing.T) {
+	cached := validEmailJSON("sender@example.com", "me@test.com", "Invoice", "Please pay.")
+	cachedHash := computeExpectedHash(cached)
+
+	tests := []struct {
+		name   string
+		prefix string
+		body   string
+	}{
+		{name: "prompt prefix change", prefix: "Be brief.", body: cached},
+		{
+			name: "same body, other subject",
+			body: validEmailJSON("sender@example.com", "me@test.com", "Receipt", "Please pay."),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usePromptWrap(t, tt.prefix, "")
+			mockDB := new(mocks.MockDataBaseServiceClient)
+			mockLLM := new(mocks.MockLLMSummaryServiceClient)
+			mockTodo := new(mocks.MockTodoServiceClient)
+
+			// Only the original email's key is cached
+			mockDB.On("CheckExist", mock.Anything, mock.MatchedBy(func(req *pb.CheckExistRequest) bool {
+				return req.HashId == cachedHash
+			}), mock.Anything).Return(&pb.CheckExistResponse{
+				Entry: &pb.DataBaseSchema{Summary: "Stale cached summary"},
+			}, nil).Maybe()
+			mockDB.On("CheckExist", mock.Anything, mock.MatchedBy(func(req *pb.CheckExistRequest) bool {
+				return req.HashId != cachedHash
+			}), mock.Anything).Return(&pb.CheckExistResponse{}, nil)
+			mockLLM.On("Summarize", mock.Anything, mock.Anything, mock.Anything).
+				Return(&pb.LLMSummaryResponse{Summary: "Fresh summary"}, nil)
+			mockTodo.On("PopulateTodo", mock.Anything, mock.Anything, mock.Anything).
+				Return(&pb.TodoResponse{}, nil)
+			mockDB.On("Write", mock.Anything, mock.Anything, mock.Anything).
+				Return(&pb.WriteResponse{}, nil)
+
+			w, router := setupUpdateTodoTest(mockDB, mockLLM, mockTodo)
+			req, _ := http.NewRequest(http.MethodPost, "/api/updatetodo", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			mockLLM.AssertNumberOfCalls(t, "Summarize", 1)
+			assert.Contains(t, w.Body.String(), "Fresh summary")
+		})
+	}
+}
+
+func TestRenderTodoDescription(t *testing.T) {
+	tmpl, err := template.New("todoDescription").Funcs(descriptionFuncs).Parse(descriptionTmpl)
+	require.NoError(t, err)
+
+	base := utils.MailInfo{
+		From:    "sender@example.com",
+		To:      "me@example.com",
+		Date:    "Mon, 1 Jan 2024",
+		Subject: "Quarterly report",
+		Content: "Summary text",
+	}
+
+	t.Run("without extra fields", func(t *testing.T) {
+		got, err := renderTodoDescription(tmpl, base)
+		require.NoError(t, err)
+		assert.Equal(t, "**FROM: sender@example.com**\n**DATE: Mon, 1 Jan 2024**\n**RECEIVED: me@example.com**\n"+
+			"**SUBJECT: Quarterly report**\n\n========================\nSummary text", got)
+	})
+
+	t.Run("with message id and attachments", func(t *testing.T) {
+		info := base
+		info.MessageID = "abc123@mail.example.com"
+		info.Attachments = []utils.Attachment{
+			{FileName: "report.pdf", URL: "https://files.example.com/report.pdf"},
+			{FileName: "inline.png"},
+		}
+		got, err := renderTodoDescription(tmpl, info)
+		require.NoError(t, err)
+		assert.Contains(t, got, "**SUBJECT: Quarterly report**\n**MESSAGE-ID: abc123@mail.example.com**\n")
+		assert.True(t, strings.HasSuffix(got, "Summary text\n\n========================\n**ATTACHMENTS:**\n"+
+			"- [report.pdf](https://files.example.com/report.pdf)\n- inline.png"), got)
+	})
+
+	t.Run("attachment links are not HTML-escaped", func(t *testing.T) {
+		info := base
+		info.Attachments = []utils.Attachment{
+			{FileName: "Q&A.pdf", URL: "https://files.example.com/qa.pdf?X-Amz-A=1&X-Amz-Sig=2"},
+			{FileName: "R&D notes.txt"},
+		}
+		got, err := renderTodoDescription(tmpl, info)
+		require.NoError(t, err)
+		assert.True(t, strings.HasSuffix(got, "**ATTACHMENTS:**\n"+
+			"- [Q&A.pdf](https://files.example.com/qa.pdf?X-Amz-A=1&X-Amz-Sig=2)\n- R&D notes.txt"), got)
+	})
+}
+
+func TestHandleUpdateTodo_KeepURLs(t *testing.T) {
+	tests := []struct {
+		name        string
+		query       string
+		wantContent string
+	}{
+		{name: "stripped by default", query: "", wantContent: "Read (): notes"},
+		{
+			name:        "kept when requested",
+			query:       "?keep_urls=true",
+			wantContent: "Read (https://docs.example.com/d/1): notes",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDB := new(mocks.MockDataBaseServiceClient)
+			mockLLM := new(mocks.MockLLMSummaryServiceClient)
+			mockTodo := new(mocks.MockTodoServiceClient)
+
+			mockDB.On("CheckExist", mock.Anything, mock.Anything, mock.Anything).
+				Return(&pb.CheckExistResponse{}, nil)
+			mockLLM.On("Summarize", mock.Anything, mock.MatchedBy(func(req *pb.LLMSummaryRequest) bool {
+				return strings.HasSuffix(req.Text, "CONTENT:\n"+tt.wantContent)
+			}), mock.Anything).
+				Return(&pb.LLMSummaryResponse{Summary: "A summary"}, nil)
+			mockTodo.On("PopulateTodo", mock.Anything, mock.Anything, mock.Anything).
+				Return(&pb.TodoResponse{}, nil)
+			mockDB.On("Write", mock.Anything, mock.Anything, mock.Anything).
+				Return(&pb.WriteResponse{}, nil)
+
+			w, router := setupUpdateTodoTest(mockDB, mockLLM, mockTodo)
+			body := `{"headers": {"from": "a@example.com", "to": "me@test.com", "subject": "Doc"},` +
+				`"plain": "Read (https://docs.example.com/d/1): notes"}`
+			req, _ := http.NewRequest(http.MethodPost, "/api/updatetodo"+tt.query, strings.NewReader(body))
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusOK, w.Code)
+			mockLLM.AssertExpectations(t)
+		})
+	}
+}
+
+func TestHandleUpdateTodo_EmailParseOptions(t *testing.T) {
+	original := emailParseOptions
+	emailParseOptions = utils.ParseOptions{DropImages: true, FlattenTables: true}
+	t.Cleanup(func() { emailParseOptions = original })
+
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockLLM := new(mocks.MockLLMSummaryServiceClient)
+	mockTodo := new(mocks.MockTodoServiceClient)
+
+	mockDB.On("CheckExist", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.CheckExistResponse{}, nil)
+	mockLLM.On("Summarize", mock.Anything, mock.MatchedBy(func(req *pb.LLMSummaryRequest) bool {
+		return strings.HasSuffix(req.Text, "CONTENT:\nItem | Price\nTea | $3")
+	}), mock.Anything).
+		Return(&pb.LLMSummaryResponse{Summary: "A summary"}, nil)
+	mockTodo.On("PopulateTodo", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.TodoResponse{}, nil)
+	mockDB.On("Write", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.WriteResponse{}, nil)
+
+	w, router := setupUpdateTodoTest(mockDB, mockLLM, mockTodo)
+	body := `{"headers": {"from": "a@example.com", "to": "me@test.com", "subject": "Receipt"},` +
+		`"html": "<img src=\"https://cdn.example.com/logo.png\" alt=\"logo\">` +
+		`<table><tr><th>Item</th><th>Price</th></tr><tr><td>Tea</td><td>$3</td></tr></table>"}`
+	req, _ := http.NewRequest(http.MethodPost, "/api/updatetodo", strings.NewReader(body))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockLLM.AssertExpectations(t)
+}
+
+func TestHandleUpdateTodo_PromptPrefixAndSuffix(t *testing.T) {
+	usePromptWrap(t, "Be brief.", "Always mention deadlines.")
+	wantPrompt := "Be brief.\n\n" + utils.DefaultPromptToSummaryEmail + "\n\nAlways mention deadlines."
+
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockLLM := new(mocks.MockLLMSummaryServiceClient)
+	mockTodo := new(mocks.MockTodoServiceClient)
+
+	mockDB.On("CheckExist", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.CheckExistResponse{}, nil)
+	mockLLM.On("Summarize", mock.Anything, mock.MatchedBy(func(req *pb.LLMSummaryRequest) bool {
+		return req.Prompt == wantPrompt
+	}), mock.Anything).
+		Return(&pb.LLMSummaryResponse{Summary: "A summary"}, nil)
+	mockTodo.On("PopulateTodo", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.TodoResponse{}, nil)
+	mockDB.On("Write", mock.Anything, mock.MatchedBy(func(req *pb.WriteRequest) bool {
+		return req.Schema.Prompt == wantPrompt
+	}), mock.Anything).
+		Return(&pb.WriteResponse{}, nil)
+
+	w, router := setupUpdateTodoTest(mockDB, mockLLM, mockTodo)
+	body := validEmailJSON("sender@example.com", "me@test.com", "Test Subject", "Test content")
+	req, _ := http.NewRequest(http.MethodPost, "/api/updatetodo", strings.NewReader(body))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockLLM.AssertExpectations(t)
+	mockDB.AssertExpectations(t)
+}
+
+func TestHandleUpdateTodo_InvalidKeepURLs(t *testing.T) {
+	w, router := setupUpdateTodoTest(new(mocks.MockDataBaseServiceClient), nil, nil)
+	body := validEmailJSON("sender@example.com", "me@test.com", "Test Subject", "Test content")
+	req, _ := http.NewRequest(http.MethodPost, "/api/updatetodo?keep_urls=maybe", strings.NewReader(body))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid keep_urls parameter")
+}
+
+func TestHandleUpdateTodo_LLMStatusMapping(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{
+			"token limit", status.Error(codes.ResourceExhausted, "daily token limit exceeded"),
+			http.StatusTooManyRequests,
+		},
+		{"invalid argument", status.Error(codes.InvalidArgument, "gemini-api-key is empty"), http.StatusBadRequest},
+		{"generation failure", status.Error(codes.Internal, "all models failed"), http.StatusInternalServerError},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mockDB := new(mocks.MockDataBaseServiceClient)
+			mockDB.On("CheckExist", mock.Anything, mock.Anything, mock.Anything).
+				Return(&pb.CheckExistResponse{Entry: nil}, nil)
+			mockLLM := new(mocks.MockLLMSummaryServiceClient)
+			mockLLM.On("Summarize", mock.Anything, mock.Anything, mock.Anything).Return(nil, tc.err)
+
+			w, router := setupUpdateTodoTest(mockDB, mockLLM, nil)
+			body := validEmailJSON("sender@example.com", "me@test.com", "Test Subject", "Test content")
+			req, _ := http.NewRequest(http.MethodPost, "/api/updatetodo", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tc.wantCode, w.Code)
+			assert.Contains(t, w.Body.String(), "error in summarizing email")
+			assertUpdateTodoSteps(t, w, false, false, false)
+		})
+	}
+}
+
+func TestHandleUpdateTodo_SummaryIncludesEmailContext(t *testing.T) {
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockLLM := new(mocks.MockLLMSummaryServiceClient)
+	mockTodo := new(mocks.MockTodoServiceClient)
+
+	mockDB.On("CheckExist", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.CheckExistResponse{}, nil)
+	var sentText string
+	mockLLM.On("Summarize", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			sentText = args.Get(1).(*pb.LLMSummaryRequest).Text
+		}).
+		Return(&pb.LLMSummaryResponse{Summary: "A summary"}, nil)
+	mockTodo.On("PopulateTodo", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.TodoResponse{}, nil)
+	mockDB.On("Write", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.WriteResponse{}, nil)
+
+	w, router := setupUpdateTodoTest(mockDB, mockLLM, mockTodo)
+	body := validEmailJSON("boss@example.com", "me@test.com", "Fwd: Q3 plan", "Please review by Friday")
+	req, _ := http.NewRequest(http.MethodPost, "/api/updatetodo", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t,
+		"SUBJECT: Fwd: Q3 plan\nFROM: boss@example.com\nTO: me@test.com\n\nCONTENT:\nPlease review by Friday",
+		sentText)
+}
+
+func TestHandleUpdateTodo_AnnotateTaskModel(t *testing.T) {
+	original := annotateTaskModel
+	annotateTaskModel = true
+	t.Cleanup(func() { annotateTaskModel = original })
+
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockLLM := new(mocks.MockLLMSummaryServiceClient)
+	mockTodo := new(mocks.MockTodoServiceClient)
+
+	mockDB.On("CheckExist", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.CheckExistResponse{}, nil)
+	mockLLM.On("Summarize", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.LLMSummaryResponse{Summary: "A summary", Model: pb.Model_MODEL_GEMINI_2_5_FLASH}, nil)
+	mockTodo.On("PopulateTodo", mock.Anything, mock.MatchedBy(func(req *pb.TodoRequest) bool {
+		return strings.HasSuffix(req.Body, "\n\n— summarized by gemini-2.5-flash")
+	}), mock.Anything).
+		Return(&pb.TodoResponse{}, nil)
+	mockDB.On("Write", mock.Anything, mock.MatchedBy(func(req *pb.WriteRequest) bool {
+		return !strings.Contains(req.Schema.Summary, "summarized by")
+	}), mock.Anything).
+		Return(&pb.WriteResponse{}, nil)
+
+	w, router := setupUpdateTodoTest(mockDB, mockLLM, mockTodo)
+	body := validEmailJSON("sender@example.com", "me@test.com", "Test Subject", "Test content")
+	req, _ := http.NewRequest(http.MethodPost, "/api/updatetodo", strings.NewReader(body))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	mockTodo.AssertExpectations(t)
+	mockDB.AssertExpectations(t)
+}
+
+func TestTaskBody(t *testing.T) {
+	original := annotateTaskModel
+	t.Cleanup(func() { annotateTaskModel = original })
+
+	annotateTaskModel = false
+	assert.Equal(t, "body\n", taskBody("body\n", pb.Model_MODEL_GEMINI_2_5_PRO))
+
+	annotateTaskModel = true
+	assert.Equal(t, "body\n\n— summarized by gemini-2.5-pro", taskBody("body\n", pb.Model_MODEL_GEMINI_2_5_PRO))
+	assert.Equal(t, "body\n", taskBody("body\n", pb.Model_MODEL_UNSPECIFIED))
+}
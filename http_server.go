@@ -0,0 +1,46 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// httpServerTimeouts bound how long a client connection may spend on each phase; zero means no limit.
+type httpServerTimeouts struct {
+	Read  time.Duration
+	Write time.Duration
+	Idle  time.Duration
+}
+
+// serverTimeouts is applied to the gateway's http.Server; set from the --http-*-timeout flags.
+var serverTimeouts httpServerTimeouts
+
+// httpServerRunner serves a handler on an explicit http.Server instead of gin's timeout-less Engine.Run.
+type httpServerRunner struct {
+	handler  http.Handler
+	timeouts httpServerTimeouts
+}
+
+func newHTTPServer(addr string, handler http.Handler, timeouts httpServerTimeouts) *http.Server {
+	return &http.Server{
+		Addr:         addr,
+		Handler:      handler,
+		ReadTimeout:  timeouts.Read,
+		WriteTimeout: timeouts.Write,
+		IdleTimeout:  timeouts.Idle,
+	}
+}
+
+// Run listens on the first address (":8080" when none is given, matching gin) until the server fails.
+func (r *httpServerRunner) Run(addr ...string) error {
+	listenAddr := ":8080"
+	if len(addr) > 0 {
+		listenAddr = addr[0]
+	}
+	err := newHTTPServer(listenAddr, r.handler, r.timeouts).ListenAndServe()
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHTTPServer_AppliesTimeouts(t *testing.T) {
+	handler := http.NewServeMux()
+	srv := newHTTPServer(":9090", handler, httpServerTimeouts{
+		Read:  5 * time.Second,
+		Write: 10 * time.Second,
+		Idle:  15 * time.Second,
+	})
+
+	assert.Equal(t, ":9090", srv.Addr)
+	assert.Equal(t, handler, srv.Handler)
+	assert.Equal(t, 5*time.Second, srv.ReadTimeout)
+	assert.Equal(t, 10*time.Second, srv.WriteTimeout)
+	assert.Equal(t, 15*time.Second, srv.IdleTimeout)
+}
+
+func TestNewHTTPServer_ReadTimeoutDropsSlowClient(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	srv := newHTTPServer("", handler, httpServerTimeouts{Read: 100 * time.Millisecond})
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(func() { _ = srv.Close() })
+
+	t.Run("complete request is served", func(t *testing.T) {
+		resp, err := http.Get("http://" + lis.Addr().String() + "/")
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	})
+
+	t.Run("stalled request is closed", func(t *testing.T) {
+		conn, err := net.Dial("tcp", lis.Addr().String())
+		require.NoError(t, err)
+		defer conn.Close()
+
+		// The header block is never terminated, as a slow-loris client would do
+		_, err = io.WriteString(conn, "GET / HTTP/1.1\r\nHost: test\r\n")
+		require.NoError(t, err)
+
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		_, err = bufio.NewReader(conn).ReadByte()
+		require.Error(t, err)
+		assert.ErrorIs(t, err, io.EOF, "server should close the connection before the client deadline")
+	})
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ziyixi/todofy/testutils"
+	"google.golang.org/grpc"
+
+	pb "github.com/ziyixi/protos/go/todofy"
+)
+
+// The real llm, todo and database servers live in their own main packages and can't be imported,
+// so these in-process stand-ins serve the same RPCs over bufconn for the gateway wiring tests.
+
+type integrationLLMServer struct {
+	pb.UnimplementedLLMSummaryServiceServer
+	mu       sync.Mutex
+	requests []*pb.LLMSummaryRequest
+}
+
+func (s *integrationLLMServer) Summarize(
+	_ context.Context, req *pb.LLMSummaryRequest,
+) (*pb.LLMSummaryResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req)
+	return &pb.LLMSummaryResponse{Summary: "Reply to the budget email.", Model: pb.Model_MODEL_GEMINI_2_5_FLASH}, nil
+}
+
+type integrationTodoServer struct {
+	pb.UnimplementedTodoServiceServer
+	mu       sync.Mutex
+	requests []*pb.TodoRequest
+}
+
+func (s *integrationTodoServer) PopulateTodo(_ context.Context, req *pb.TodoRequest) (*pb.TodoResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, req)
+	return &pb.TodoResponse{Id: "task-1"}, nil
+}
+
+type integrationDatabaseServer struct {
+	pb.UnimplementedDataBaseServiceServer
+	mu      sync.Mutex
+	entries map[string]*pb.DataBaseSchema
+}
+
+func (s *integrationDatabaseServer) Write(_ context.Context, req *pb.WriteRequest) (*pb.WriteResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[req.Schema.HashId] = req.Schema
+	return &pb.WriteResponse{}, nil
+}
+
+func (s *integrationDatabaseServer) CheckExist(
+	_ context.Context, req *pb.CheckExistRequest,
+) (*pb.CheckExistResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return &pb.CheckExistResponse{Entry: s.entries[req.HashId]}, nil
+}
+
+type integrationBackends struct {
+	llm      *integrationLLMServer
+	todo     *integrationTodoServer
+	database *integrationDatabaseServer
+}
+
+// newBufconnGRPCClients serves every backend on its own bufconn listener and connects a real
+// GRPCClients to them through the production service configs.
+func newBufconnGRPCClients(t *testing.T) (*GRPCClients, *integrationBackends) {
+	t.Helper()
+
+	backends := &integrationBackends{
+		llm:      &integrationLLMServer{},
+		todo:     &integrationTodoServer{},
+		database: &integrationDatabaseServer{entries: map[string]*pb.DataBaseSchema{}},
+	}
+	serve := func(register func(grpc.ServiceRegistrar)) grpc.DialOption {
+		return grpc.WithContextDialer(testutils.BufDialer(testutils.ServeBufconn(t, register)))
+	}
+	dialers := map[string]grpc.DialOption{
+		"llm": serve(func(s grpc.ServiceRegistrar) {
+			pb.RegisterLLMSummaryServiceServer(s, backends.llm)
+		}),
+		"todo": serve(func(s grpc.ServiceRegistrar) {
+			pb.RegisterTodoServiceServer(s, backends.todo)
+		}),
+		"database": serve(func(s grpc.ServiceRegistrar) {
+			pb.RegisterDataBaseServiceServer(s, backends.database)
+		}),
+		"dependency": serve(func(s grpc.ServiceRegistrar) {
+			pb.RegisterDependencyServiceServer(s, &pb.UnimplementedDependencyServiceServer{})
+		}),
+	}
+
+	configs := buildServiceConfigs(Config{
+		LLMAddr:             "passthrough:///llm",
+		TodoAddr:            "passthrough:///todo",
+		DatabaseAddr:        "passthrough:///database",
+		DependencyAddr:      "passthrough:///dependency",
+		HealthCheckInterval: 50,
+	})
+	for i := range configs {
+		configs[i].dialOptions = []grpc.DialOption{dialers[configs[i].name]}
+	}
+
+	clients, err := NewGRPCClients(configs)
+	require.NoError(t, err)
+	t.Cleanup(clients.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	require.NoError(t, clients.WaitForHealthy(ctx))
+	return clients, backends
+}
+
+func TestIntegration_UpdateTodoSummarizesCreatesTodoAndWrites(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	clients, backends := newBufconnGRPCClients(t)
+
+	router := gin.New()
+	router.Use(grpcMiddleware(clients))
+	router.POST("/api/updatetodo", HandleUpdateTodo)
+
+	body := validEmailJSON("alice@example.com", "todo@example.com", "Budget", "Please review the budget.")
+	post := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/api/updatetodo", strings.NewReader(body))
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := post()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assertUpdateTodoSteps(t, w, true, true, true)
+
+	require.Len(t, backends.llm.requests, 1)
+	assert.Contains(t, backends.llm.requests[0].Text, "SUBJECT: Budget")
+	require.Len(t, backends.todo.requests, 1)
+	assert.Equal(t, "Budget", backends.todo.requests[0].Subject)
+	assert.Contains(t, backends.todo.requests[0].Body, "Reply to the budget email.")
+	stored := backends.database.entries[computeExpectedHash(body)]
+	require.NotNil(t, stored)
+	assert.Equal(t, pb.Model_MODEL_GEMINI_2_5_FLASH, stored.Model)
+
+	// The same email again is served from the database cache without another LLM call
+	w = post()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Len(t, backends.llm.requests, 1)
+	assert.Len(t, backends.todo.requests, 2)
+}
+
+func TestIntegration_ReprocessDeadLetteredEmail(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	clients, backends := newBufconnGRPCClients(t)
+	dir := t.TempDir()
+	useDeadLetterDir(t, dir)
+
+	body := validEmailJSON("alice@example.com", "todo@example.com", "Invoice", "Please pay the invoice.")
+	require.NoError(t, writeDeadLetter(dir, time.Now(), []byte(body), http.StatusInternalServerError,
+		gin.H{"error in creating todo": "todoist unavailable"}))
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	require.Len(t, paths, 1)
+	id := strings.TrimSuffix(filepath.Base(paths[0]), ".json")
+
+	router := gin.New()
+	router.Use(grpcMiddleware(clients))
+	router.POST("/api/reprocess/:id", HandleReprocess)
+	reprocess := func(id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/api/reprocess/"+id, nil))
+		return w
+	}
+
+	w := reprocess(id)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assertUpdateTodoSteps(t, w, true, true, true)
+	require.Len(t, backends.llm.requests, 1)
+	assert.Contains(t, backends.llm.requests[0].Text, "SUBJECT: Invoice")
+	require.Len(t, backends.todo.requests, 1)
+	assert.Equal(t, "Invoice", backends.todo.requests[0].Subject)
+	assert.NotNil(t, backends.database.entries[computeExpectedHash(body)])
+	assert.NoFileExists(t, paths[0], "a successful replay removes the record")
+
+	assert.Equal(t, http.StatusNotFound, reprocess(id).Code)
+	assert.Equal(t, http.StatusBadRequest, reprocess("..secrets").Code)
+}
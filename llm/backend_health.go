@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/genai"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// geminiHealthService is the gRPC health service name reporting whether Gemini accepts the
+// configured credentials and is reachable. The overall ("") status is unaffected; the
+// gateway's /readyz checks this name too (backendHealthServices).
+const geminiHealthService = "gemini"
+
+const backendHealthCheckTimeout = 15 * time.Second
+
+// geminiHealthProbeText is counted by the health check; CountTokens is free and is never
+// recorded against the daily token budget.
+const geminiHealthProbeText = "ping"
+
+// checkGeminiBackend reports SERVING only when credentials are configured and a CountTokens
+// call with the first priority model succeeds.
+func (s *llmServer) checkGeminiBackend(ctx context.Context) healthpb.HealthCheckResponse_ServingStatus {
+	if *geminiAPIKey == "" && *geminiBackend != geminiBackendVertexAI {
+		log.Warn("Gemini backend health: no API key configured")
+		return healthpb.HealthCheckResponse_NOT_SERVING
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, backendHealthCheckTimeout)
+	defer cancel()
+	factory := s.clientFactory
+	if factory == nil {
+		factory = newRealGeminiClient
+	}
+	client, err := factory(ctx, *geminiAPIKey)
+	if err != nil {
+		log.Warnf("Gemini backend health check failed to create a client: %v", err)
+		return healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	contents := []*genai.Content{{Parts: []*genai.Part{{Text: geminiHealthProbeText}}}}
+	if _, err := client.CountTokens(ctx, llmModelNames[llmModelPriority[0]], contents); err != nil {
+		log.Warnf("Gemini backend health check failed: %v", err)
+		return healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	return healthpb.HealthCheckResponse_SERVING
+}
+
+// runBackendHealthChecks publishes Gemini status on healthServer right away and then on
+// every interval until ctx is done. An interval of 0 checks once.
+func (s *llmServer) runBackendHealthChecks(ctx context.Context, healthServer *health.Server, interval time.Duration) {
+	healthServer.SetServingStatus(geminiHealthService, s.checkGeminiBackend(ctx))
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			healthServer.SetServingStatus(geminiHealthService, s.checkGeminiBackend(ctx))
+		}
+	}
+}
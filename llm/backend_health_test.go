@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func geminiHealthStatus(t *testing.T, healthServer *health.Server) healthpb.HealthCheckResponse_ServingStatus {
+	t.Helper()
+	resp, err := healthServer.Check(context.Background(), &healthpb.HealthCheckRequest{Service: geminiHealthService})
+	require.NoError(t, err)
+	return resp.Status
+}
+
+func TestRunBackendHealthChecks(t *testing.T) {
+	originalKey := *geminiAPIKey
+	t.Cleanup(func() { *geminiAPIKey = originalKey })
+
+	tests := []struct {
+		name      string
+		apiKey    string
+		countErr  error
+		clientErr error
+		want      healthpb.HealthCheckResponse_ServingStatus
+		wantCalls int
+	}{
+		{name: "reachable", apiKey: testAPIKey, want: healthpb.HealthCheckResponse_SERVING, wantCalls: 1},
+		{
+			name:      "rejected api key",
+			apiKey:    testAPIKey,
+			countErr:  errors.New("API key not valid"),
+			want:      healthpb.HealthCheckResponse_NOT_SERVING,
+			wantCalls: 1,
+		},
+		{
+			name:      "client creation fails",
+			apiKey:    testAPIKey,
+			clientErr: errors.New("unreachable"),
+			want:      healthpb.HealthCheckResponse_NOT_SERVING,
+		},
+		{name: "missing api key", want: healthpb.HealthCheckResponse_NOT_SERVING},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := &fakeGeminiClient{
+				countTokens: func(context.Context, string, []*genai.Content) (*genai.CountTokensResponse, error) {
+					if tt.countErr != nil {
+						return nil, tt.countErr
+					}
+					return &genai.CountTokensResponse{TotalTokens: 1}, nil
+				},
+			}
+			server := setupTestServer(fake, 1000)
+			*geminiAPIKey = tt.apiKey
+			if tt.clientErr != nil {
+				server.clientFactory = newFailingClientFactory(tt.clientErr)
+			}
+			healthServer := health.NewServer()
+
+			server.runBackendHealthChecks(context.Background(), healthServer, 0)
+
+			assert.Equal(t, tt.want, geminiHealthStatus(t, healthServer))
+			assert.Equal(t, tt.wantCalls, fake.countTokensCalls)
+			assert.Equal(t, int32(0), server.tracker.CurrentUsage(), "health probes do not use the token budget")
+		})
+	}
+}
@@ -1,25 +1,32 @@
 // Package llm provides constants and configuration for language model operations.
 package main
 
-import pb "github.com/ziyixi/protos/go/todofy"
+import (
+	pb "github.com/ziyixi/protos/go/todofy"
+	"github.com/ziyixi/todofy/utils"
+)
 
 var (
-	llmModelNames = map[pb.Model]string{
-		pb.Model_MODEL_GEMINI_2_5_PRO:         "gemini-2.5-pro",
-		pb.Model_MODEL_GEMINI_2_5_FLASH:       "gemini-2.5-flash",
-		pb.Model_MODEL_GEMINI_2_5_FLASH_LITE:  "gemini-2.5-flash-lite",
-		pb.Model_MODEL_GEMINI_3_FLASH_PREVIEW: "gemini-3-flash-preview",
-	}
-	llmModelPriority = []pb.Model{
-		pb.Model_MODEL_GEMINI_2_5_FLASH_LITE,
-		pb.Model_MODEL_GEMINI_2_5_FLASH,
-		pb.Model_MODEL_GEMINI_3_FLASH_PREVIEW,
-	}
-	supportedModelFamily = []pb.ModelFamily{
-		pb.ModelFamily_MODEL_FAMILY_GEMINI,
+	llmModelNames        = utils.LLMModelNames
+	llmModelPriority     = utils.LLMModelPriority
+	supportedModelFamily = utils.SupportedModelFamilies
+
+	// llmModelContextWindows is each model's input token limit; MaxTokens may not exceed it.
+	// Every model supported today has the same published limit, so the entries only differ
+	// once a model with a smaller window is added. Tests override entries to exercise truncation.
+	llmModelContextWindows = map[pb.Model]int32{
+		pb.Model_MODEL_GEMINI_2_5_PRO:         geminiInputTokenLimit,
+		pb.Model_MODEL_GEMINI_2_5_FLASH:       geminiInputTokenLimit,
+		pb.Model_MODEL_GEMINI_2_5_FLASH_LITE:  geminiInputTokenLimit,
+		pb.Model_MODEL_GEMINI_3_FLASH_PREVIEW: geminiInputTokenLimit,
 	}
 )
 
 const (
-	tokenLimit int32 = 1048576 // ~1M tokens (~1,048,576)
+	// geminiInputTokenLimit is the input token limit Google documents for the Gemini 2.5 and
+	// Gemini 3 Flash models (1,048,576 tokens).
+	geminiInputTokenLimit int32 = 1048576
+
+	// tokenLimit is the truncation budget for models missing from llmModelContextWindows.
+	tokenLimit = geminiInputTokenLimit
 )
@@ -96,3 +96,11 @@ func TestModelFamilySupport(t *testing.T) {
 		}
 	})
 }
+
+func TestLLMModelContextWindows(t *testing.T) {
+	for model := range llmModelNames {
+		window, ok := llmModelContextWindows[model]
+		assert.True(t, ok, "model %v should have a context window", model)
+		assert.LessOrEqual(t, tokenLimit, window, "default budget must fit model %v", model)
+	}
+}
@@ -2,4 +2,16 @@
 
 /llm \
     -port=${PORT} \
-    -gemini-api-key=${GEMINI_API_KEY}
\ No newline at end of file
+    -gemini-api-key=${GEMINI_API_KEY} \
+    -gemini-api-key-file=${GEMINI_API_KEY_FILE} \
+    -gemini-backend=${GEMINI_BACKEND:-gemini} \
+    -gcp-project=${GCP_PROJECT} \
+    -gcp-location=${GCP_LOCATION} \
+    -max-concurrent-requests=${MAX_CONCURRENT_REQUESTS:-0} \
+    -max-summary-runes=${MAX_SUMMARY_RUNES:-0} \
+    -skip-token-count-below-chars=${SKIP_TOKEN_COUNT_BELOW_CHARS:-0} \
+    -rate-limit-max-wait=${RATE_LIMIT_MAX_WAIT:-0s} \
+    -unknown-model-fallback=${UNKNOWN_MODEL_FALLBACK:-false} \
+    -backend-health-interval=${BACKEND_HEALTH_INTERVAL:-5m} \
+    -grpc-auth-token=${GRPC_AUTH_TOKEN} \
+    -enable-reflection=${ENABLE_GRPC_REFLECTION:-true}
\ No newline at end of file
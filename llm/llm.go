@@ -4,13 +4,21 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"net"
+	"os"
 	"slices"
+	"strconv"
+	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/sirupsen/logrus"
 	"github.com/ziyixi/todofy/utils"
 	"google.golang.org/genai"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
 	pb "github.com/ziyixi/protos/go/todofy"
@@ -18,6 +26,7 @@ import (
 
 var log = logrus.New()
 var GitCommit string // Will be set by Bazel at build time
+var BuildDate string // Will be set via -ldflags at build time
 
 // initLogger initializes the logger configuration
 func initLogger() {
@@ -27,20 +36,70 @@ func initLogger() {
 }
 
 var (
-	port            = flag.Int("port", 50051, "The server port of the LLM service")
-	geminiAPIKey    = flag.String("gemini-api-key", "", "The API key for Gemini")
+	showVersion      = flag.Bool("version", false, "Print version information and exit")
+	port             = flag.Int("port", 50051, "The server port of the LLM service")
+	geminiAPIKey     = flag.String("gemini-api-key", "", "The API key for Gemini")
+	geminiAPIKeyFile = flag.String(
+		"gemini-api-key-file", "", "File containing the Gemini API key (--gemini-api-key takes precedence)",
+	)
+	geminiBackend = flag.String(
+		"gemini-backend", geminiBackendGeminiAPI,
+		"Gemini API backend: gemini (API key) or vertex (Vertex AI with application default credentials)",
+	)
+	gcpProject      = flag.String("gcp-project", "", "GCP project ID for the vertex Gemini backend")
+	gcpLocation     = flag.String("gcp-location", "", "GCP location (e.g. us-central1) for the vertex Gemini backend")
 	dailyTokenLimit = flag.Int(
 		"daily-token-limit", 3000000,
 		"Maximum tokens allowed per 24h sliding window (0 = unlimited)",
 	)
+	maxConcurrentRequests = flag.Int(
+		"max-concurrent-requests", 0,
+		"Maximum concurrent Gemini API calls; extra requests queue (0 = unlimited)",
+	)
+	maxSummaryRunes = flag.Int(
+		"max-summary-runes", 0,
+		"Truncate generated summaries longer than this many characters, ending them with an ellipsis (0 = no cap)",
+	)
+	skipTokenCountBelowChars = flag.Int(
+		"skip-token-count-below-chars", 0,
+		"Skip the CountTokens call for prompts plus text shorter than this many bytes (0 = always count)",
+	)
+	rateLimitMaxWait = flag.Duration(
+		"rate-limit-max-wait", 0,
+		"Retry a rate-limited Gemini model after its suggested delay when it is at most this long, "+
+			"instead of moving on to the next model (0 = always move on)",
+	)
+	unknownModelFallback = flag.Bool(
+		"unknown-model-fallback", false,
+		"Use the default model priority when a request names a model this service doesn't know, instead of failing",
+	)
+	backendHealthInterval = flag.Duration(
+		"backend-health-interval",
+		5*time.Minute,
+		"How often to re-check Gemini connectivity for the per-backend health status (0 = check once)",
+	)
+	enableReflection = flag.Bool("enable-reflection", true, "Register the gRPC reflection service")
+	grpcAuthToken    = flag.String(
+		"grpc-auth-token", "", "Shared secret callers must send as a bearer token (empty disables auth)",
+	)
 )
 
 const maxInt32Value = int(^uint32(0) >> 1)
 
 type llmServer struct {
 	pb.LLMSummaryServiceServer
-	tracker       *TokenTracker
-	clientFactory func(ctx context.Context, apiKey string) (geminiClient, error)
+	tracker     *TokenTracker
+	concurrency *utils.Semaphore
+	// maxSummaryRunes caps the returned summary length; 0 disables the cap.
+	maxSummaryRunes int
+	// skipTokenCountBelowChars skips CountTokens for inputs shorter than this many bytes; 0 always counts.
+	skipTokenCountBelowChars int
+	// rateLimitMaxWait is the longest Gemini-suggested retry delay waited out on the same model;
+	// 0 moves on to the next model straight away.
+	rateLimitMaxWait time.Duration
+	// unknownModelFallback serves requests for unknown models with llmModelPriority instead of failing them.
+	unknownModelFallback bool
+	clientFactory        func(ctx context.Context, apiKey string) (geminiClient, error)
 }
 
 // geminiClient abstracts the Gemini API for testing.
@@ -70,11 +129,41 @@ func (c *realGeminiClient) GenerateContent(
 	return c.client.Models.GenerateContent(ctx, model, contents, nil)
 }
 
+const (
+	geminiBackendGeminiAPI = "gemini"
+	geminiBackendVertexAI  = "vertex"
+)
+
+// geminiClientConfig builds the genai client config for the selected backend. Vertex AI
+// authenticates with application default credentials, so the API key is not sent there.
+func geminiClientConfig(backend, apiKey, project, location string) (*genai.ClientConfig, error) {
+	switch backend {
+	case geminiBackendGeminiAPI:
+		return &genai.ClientConfig{
+			APIKey:  apiKey,
+			Backend: genai.BackendGeminiAPI,
+		}, nil
+	case geminiBackendVertexAI:
+		if project == "" || location == "" {
+			return nil, fmt.Errorf("gemini backend %q requires --gcp-project and --gcp-location", backend)
+		}
+		return &genai.ClientConfig{
+			Project:  project,
+			Location: location,
+			Backend:  genai.BackendVertexAI,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported gemini backend %q (want %q or %q)",
+			backend, geminiBackendGeminiAPI, geminiBackendVertexAI)
+	}
+}
+
 func newRealGeminiClient(ctx context.Context, apiKey string) (geminiClient, error) {
-	client, err := genai.NewClient(ctx, &genai.ClientConfig{
-		APIKey:  apiKey,
-		Backend: genai.BackendGeminiAPI,
-	})
+	config, err := geminiClientConfig(*geminiBackend, apiKey, *gcpProject, *gcpLocation)
+	if err != nil {
+		return nil, err
+	}
+	client, err := genai.NewClient(ctx, config)
 	if err != nil {
 		return nil, err
 	}
@@ -86,49 +175,146 @@ func (s *llmServer) Summarize(ctx context.Context, req *pb.LLMSummaryRequest) (*
 		return nil, status.Errorf(codes.InvalidArgument, "unsupported model family: %s", req.ModelFamily)
 	}
 
-	maxTokens := tokenLimit
-	if req.MaxTokens != 0 {
-		maxTokens = req.MaxTokens
+	// 0 lets every model truncate to its own context window
+	maxTokens := req.MaxTokens
+	if maxTokens < 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "max_tokens must be >= 0, got %d", req.MaxTokens)
+	}
+	if maxTokens != 0 {
+		// An explicitly chosen model can't stretch its context window, so fail fast instead of deep in the API
+		if window, ok := llmModelContextWindows[req.Model]; ok && maxTokens > window {
+			return nil, status.Errorf(codes.InvalidArgument,
+				"max_tokens %d exceeds the %d-token context window of %s", maxTokens, window, req.Model)
+		}
 	}
 
 	prompt := req.Prompt
 
 	selectedModels := llmModelPriority
 	if req.Model != pb.Model_MODEL_UNSPECIFIED {
-		selectedModels = []pb.Model{req.Model}
+		if _, known := llmModelNames[req.Model]; known || !s.unknownModelFallback {
+			selectedModels = []pb.Model{req.Model}
+		} else {
+			log.Warningf("Unknown model %s requested, falling back to the default model priority", req.Model)
+		}
 	}
 
-	summary, model, err := s.summaryInternal(ctx, req.ModelFamily, prompt, req.Text, selectedModels, maxTokens)
+	summary, model, failedModels, err := s.summaryInternal(
+		ctx, req.ModelFamily, prompt, req.Text, selectedModels, maxTokens,
+	)
+	setAttemptTrailer(ctx, failedModels, err == nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate summary: %v", err)
+		// Keep the status code so callers can tell quota exhaustion and bad requests from generation failures
+		st := status.Convert(err)
+		return nil, status.Errorf(st.Code(), "failed to generate summary: %s", st.Message())
 	}
 
-	return &pb.LLMSummaryResponse{Summary: summary, Model: model}, nil
+	return &pb.LLMSummaryResponse{Summary: truncateSummary(summary, s.maxSummaryRunes), Model: model}, nil
+}
+
+// setAttemptTrailer reports how many models Summarize tried and which of them failed in the
+// response trailer, so callers can monitor fallbacks without a proto change.
+func setAttemptTrailer(ctx context.Context, failedModels []pb.Model, succeeded bool) {
+	attempts := len(failedModels)
+	if succeeded {
+		attempts++
+	}
+	names := make([]string, len(failedModels))
+	for i, model := range failedModels {
+		names[i] = llmModelNames[model]
+	}
+	trailer := metadata.Pairs(
+		utils.LLMAttemptsTrailer, strconv.Itoa(attempts),
+		utils.LLMFailedModelsTrailer, strings.Join(names, ","),
+	)
+	// Fails only outside a gRPC call, e.g. when tests call Summarize directly
+	_ = grpc.SetTrailer(ctx, trailer)
+}
+
+const summaryEllipsis = "…"
+
+// truncateSummary cuts summaries longer than maxRunes runes down to maxRunes, ellipsis included.
+// It counts runes rather than bytes so the result stays valid UTF-8; maxRunes <= 0 disables it.
+func truncateSummary(summary string, maxRunes int) string {
+	if maxRunes <= 0 || utf8.RuneCountInString(summary) <= maxRunes {
+		return summary
+	}
+	runes := []rune(summary)
+	return strings.TrimRightFunc(string(runes[:maxRunes-1]), unicode.IsSpace) + summaryEllipsis
 }
 
 func (s *llmServer) summaryInternal(ctx context.Context, modelFamily pb.ModelFamily,
-	prompt, text string, models []pb.Model, maxTokens int32) (string, pb.Model, error) {
+	prompt, text string, models []pb.Model, maxTokens int32) (string, pb.Model, []pb.Model, error) {
+	var failed []pb.Model
 	for _, model := range models {
 		if _, ok := llmModelNames[model]; !ok {
-			return "", pb.Model_MODEL_UNSPECIFIED, status.Errorf(codes.InvalidArgument, "unsupported model: %s", model)
+			return "", pb.Model_MODEL_UNSPECIFIED, failed,
+				status.Errorf(codes.InvalidArgument, "unsupported model: %s", model)
 		}
 
-		summary, err := s.tryGenerateSummary(ctx, modelFamily, prompt, text, model, maxTokens)
+		modelMaxTokens := modelTokenBudget(model, maxTokens)
+
+		summary, err := s.tryGenerateSummary(ctx, modelFamily, prompt, text, model, modelMaxTokens)
+		delay, rateLimited := geminiRateLimitDelay(err)
+		if rateLimited && delay > 0 && delay <= s.rateLimitMaxWait {
+			log.Warningf("Model %s is rate limited, retrying it in %s", model, delay)
+			if err := sleepContext(ctx, delay); err != nil {
+				return "", pb.Model_MODEL_UNSPECIFIED, append(failed, model), status.FromContextError(err).Err()
+			}
+			summary, err = s.tryGenerateSummary(ctx, modelFamily, prompt, text, model, modelMaxTokens)
+			_, rateLimited = geminiRateLimitDelay(err)
+		}
+		if isTerminalSummaryError(err) {
+			log.Warningf("Error generating summary with model %s, not trying fallbacks: %v", model, err)
+			return "", pb.Model_MODEL_UNSPECIFIED, append(failed, model), err
+		}
 		if err != nil {
 			log.Warningf("Error generating summary with model %s: %v", model, err)
-			time.Sleep(time.Second)
+			failed = append(failed, model)
+			// A rate-limited model says nothing about the next one, so only pause on other failures
+			if !rateLimited {
+				time.Sleep(time.Second)
+			}
 			continue
 		}
 		if summary != "" {
 			log.Infof("Successfully generated summary with model %s", model)
-			return summary, model, nil
+			return summary, model, failed, nil
 		}
+		// An empty summary is a failed attempt too, so the next model gets a chance
+		failed = append(failed, model)
 	}
 	log.Errorf("Failed to generate summary with all models")
-	return "", pb.Model_MODEL_UNSPECIFIED, status.Errorf(codes.Internal,
+	return "", pb.Model_MODEL_UNSPECIFIED, failed, status.Errorf(codes.Internal,
 		"failed to generate summary with all models: %v", models)
 }
 
+// modelTokenBudget returns the token count content is truncated to for model. Without a requested
+// budget (maxTokens == 0) that is the model's context window; otherwise the request is clamped to
+// the window, since fallback models may have smaller windows than the requested budget.
+// Models without a known window fall back to tokenLimit.
+func modelTokenBudget(model pb.Model, maxTokens int32) int32 {
+	window, ok := llmModelContextWindows[model]
+	if !ok {
+		window = tokenLimit
+	}
+	if maxTokens == 0 {
+		return window
+	}
+	return min(maxTokens, window)
+}
+
+// isTerminalSummaryError reports whether err would fail the same way on every fallback model:
+// the daily token budget is shared, and invalid or cancelled requests don't depend on the model.
+func isTerminalSummaryError(err error) bool {
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.ResourceExhausted, codes.Canceled, codes.DeadlineExceeded:
+		return true
+	default:
+		return false
+	}
+}
+
 func (s *llmServer) tryGenerateSummary(ctx context.Context, modelFamily pb.ModelFamily,
 	prompt, text string, model pb.Model, maxTokens int32) (string, error) {
 	switch modelFamily {
@@ -141,7 +327,7 @@ func (s *llmServer) tryGenerateSummary(ctx context.Context, modelFamily pb.Model
 
 func (s *llmServer) summaryByGemini(ctx context.Context, prompt, content string,
 	llmModel pb.Model, maxTokens int32) (string, error) {
-	if *geminiAPIKey == "" {
+	if *geminiAPIKey == "" && *geminiBackend != geminiBackendVertexAI {
 		return "", status.Error(codes.InvalidArgument, "gemini-api-key is empty")
 	}
 
@@ -159,16 +345,25 @@ func (s *llmServer) summaryByGemini(ctx context.Context, prompt, content string,
 		return "", status.Errorf(codes.InvalidArgument, "unsupported model: %s", llmModel)
 	}
 
+	// Bound concurrent Gemini calls so every caller shares the same quota budget
+	if err := s.concurrency.Acquire(ctx); err != nil {
+		return "", status.FromContextError(err).Err()
+	}
+	defer s.concurrency.Release()
+
 	contentWithPrompt := fmt.Sprintf("%s\n%s", prompt, content)
 
 	// Create content for the new API
 	parts := []*genai.Part{{Text: contentWithPrompt}}
 	contents := []*genai.Content{{Parts: parts}}
 
-	// Count tokens first
-	respToken, err := client.CountTokens(ctx, llmModelName, contents)
-	if err != nil {
-		return "", fmt.Errorf("failed to count tokens: %v", err)
+	// Count tokens first, unless the input is too small to need truncation
+	respToken, skipped := s.estimateSmallInputTokens(contentWithPrompt, maxTokens)
+	if !skipped {
+		respToken, err = client.CountTokens(ctx, llmModelName, contents)
+		if err != nil {
+			return "", fmt.Errorf("failed to count tokens: %w", err)
+		}
 	}
 
 	for respToken.TotalTokens > maxTokens {
@@ -177,7 +372,7 @@ func (s *llmServer) summaryByGemini(ctx context.Context, prompt, content string,
 		contents = []*genai.Content{{Parts: parts}}
 		respToken, err = client.CountTokens(ctx, llmModelName, contents)
 		if err != nil {
-			return "", fmt.Errorf("failed to count tokens: %v", err)
+			return "", fmt.Errorf("failed to count tokens: %w", err)
 		}
 	}
 
@@ -191,7 +386,7 @@ func (s *llmServer) summaryByGemini(ctx context.Context, prompt, content string,
 
 	resp, err := client.GenerateContent(ctx, llmModelName, contents)
 	if err != nil {
-		return "", fmt.Errorf("failed to generate content: %v", err)
+		return "", fmt.Errorf("failed to generate content: %w", err)
 	}
 
 	if len(resp.Candidates) == 0 || resp.Candidates[0].Content == nil {
@@ -216,6 +411,16 @@ func (s *llmServer) summaryByGemini(ctx context.Context, prompt, content string,
 	return resp.Candidates[0].Content.Parts[0].Text, nil
 }
 
+// estimateSmallInputTokens stands in for CountTokens on inputs below skipTokenCountBelowChars.
+// A token spans at least one byte, so the byte length is an upper bound for the daily limit check;
+// inputs that could exceed maxTokens are still counted so truncation stays exact.
+func (s *llmServer) estimateSmallInputTokens(input string, maxTokens int32) (*genai.CountTokensResponse, bool) {
+	if len(input) >= s.skipTokenCountBelowChars || len(input) > int(maxTokens) {
+		return nil, false
+	}
+	return &genai.CountTokensResponse{TotalTokens: int32(len(input))}, true
+}
+
 func normalizeDailyTokenLimit(limit int) (int32, error) {
 	if limit < 0 {
 		return 0, fmt.Errorf("daily-token-limit must be >= 0, got %d", limit)
@@ -226,24 +431,84 @@ func normalizeDailyTokenLimit(limit int) (int32, error) {
 	return int32(limit), nil
 }
 
+// resolveAPIKeyFlags fills --gemini-api-key from --gemini-api-key-file when only the file is given.
+func resolveAPIKeyFlags() error {
+	key, err := utils.ResolveSecret(*geminiAPIKey, *geminiAPIKeyFile)
+	if err != nil {
+		return fmt.Errorf("invalid gemini-api-key-file: %w", err)
+	}
+	*geminiAPIKey = key
+	return nil
+}
+
 func main() {
 	initLogger()
 	flag.Parse()
 
+	if *showVersion {
+		utils.PrintVersion(os.Stdout, "todofy-llm", GitCommit, BuildDate)
+		return
+	}
+	if err := resolveAPIKeyFlags(); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	if _, err := geminiClientConfig(*geminiBackend, *geminiAPIKey, *gcpProject, *gcpLocation); err != nil {
+		log.Fatalf("invalid gemini backend flags: %v", err)
+	}
+	log.Infof("Gemini backend: %s", *geminiBackend)
+
 	normalizedDailyTokenLimit, err := normalizeDailyTokenLimit(*dailyTokenLimit)
 	if err != nil {
 		log.Fatalf("invalid daily-token-limit: %v", err)
 	}
 
+	if *skipTokenCountBelowChars < 0 {
+		log.Fatalf("invalid skip-token-count-below-chars: must be >= 0, got %d", *skipTokenCountBelowChars)
+	}
+	if *maxSummaryRunes < 0 {
+		log.Fatalf("invalid max-summary-runes: must be >= 0, got %d", *maxSummaryRunes)
+	}
+
 	tracker := NewTokenTracker(24*time.Hour, normalizedDailyTokenLimit)
 	log.Infof("Daily token limit: %d (0 = unlimited)", *dailyTokenLimit)
+	log.Infof("Max concurrent Gemini requests: %d (0 = unlimited)", *maxConcurrentRequests)
+	log.Infof("Max summary length: %d characters (0 = no cap)", *maxSummaryRunes)
+
+	llmSvc := &llmServer{
+		tracker:                  tracker,
+		concurrency:              utils.NewSemaphore(*maxConcurrentRequests),
+		maxSummaryRunes:          *maxSummaryRunes,
+		skipTokenCountBelowChars: *skipTokenCountBelowChars,
+		rateLimitMaxWait:         *rateLimitMaxWait,
+		unknownModelFallback:     *unknownModelFallback,
+		clientFactory:            newRealGeminiClient,
+	}
+	if err := runGRPCServer(llmSvc); err != nil {
+		log.Fatalf("server error: %v", err)
+	}
+}
 
-	err = utils.StartGRPCServer[pb.LLMSummaryServiceServer](
-		*port,
-		&llmServer{tracker: tracker, clientFactory: newRealGeminiClient},
-		pb.RegisterLLMSummaryServiceServer,
-	)
+func runGRPCServer(llmSvc *llmServer) error {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
 	if err != nil {
-		log.Fatalf("server error: %v", err)
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	server, healthServer := utils.NewGRPCServer(
+		utils.SharedSecretServerOption(*grpcAuthToken),
+		utils.WithReflection(*enableReflection),
+		utils.WithRPCLogger(log),
+	)
+	pb.RegisterLLMSummaryServiceServer(server, llmSvc)
+
+	backgroundCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go llmSvc.runBackendHealthChecks(backgroundCtx, healthServer, *backendHealthInterval)
+
+	log.Infof("LLM gRPC server is running on port %d", *port)
+	if err := server.Serve(lis); err != nil {
+		return fmt.Errorf("failed to serve: %w", err)
 	}
+	return nil
 }
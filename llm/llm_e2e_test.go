@@ -3,14 +3,22 @@ package main
 import (
 	"context"
 	"fmt"
+	"slices"
+	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"github.com/ziyixi/todofy/utils"
 	"google.golang.org/genai"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 
 	pb "github.com/ziyixi/protos/go/todofy"
 )
@@ -198,6 +206,106 @@ func TestE2E_Summarize_ModelFallback(t *testing.T) {
 	assert.Equal(t, pb.Model_MODEL_GEMINI_2_5_FLASH, resp.Model)
 }
 
+// trailerStream records the trailer a handler sets, standing in for a real gRPC stream.
+type trailerStream struct {
+	grpc.ServerTransportStream
+	trailer metadata.MD
+}
+
+func (s *trailerStream) Method() string { return "/todofy.LLMSummaryService/Summarize" }
+
+func (s *trailerStream) SetTrailer(md metadata.MD) error {
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
+
+func TestE2E_Summarize_FallbackAttemptTrailer(t *testing.T) {
+	originalKey := *geminiAPIKey
+	defer func() { *geminiAPIKey = originalKey }()
+
+	fake := &fakeGeminiClient{
+		generateContent: func(
+			ctx context.Context, model string,
+			contents []*genai.Content,
+		) (*genai.GenerateContentResponse, error) {
+			if model == "gemini-2.5-flash" {
+				return makeSuccessResp("Fallback summary.", 200), nil
+			}
+			return nil, fmt.Errorf("model overloaded")
+		},
+	}
+	server := setupTestServer(fake, 3000000)
+	req := &pb.LLMSummaryRequest{ModelFamily: pb.ModelFamily_MODEL_FAMILY_GEMINI, Text: "Test content"}
+
+	stream := &trailerStream{}
+	resp, err := server.Summarize(grpc.NewContextWithServerTransportStream(context.Background(), stream), req)
+	require.NoError(t, err)
+	assert.Equal(t, pb.Model_MODEL_GEMINI_2_5_FLASH, resp.Model)
+
+	failedIndex := slices.Index(llmModelPriority, pb.Model_MODEL_GEMINI_2_5_FLASH)
+	var failed []string
+	for _, model := range llmModelPriority[:failedIndex] {
+		failed = append(failed, llmModelNames[model])
+	}
+	require.NotEmpty(t, failed, "the test needs at least one model ahead of gemini-2.5-flash")
+	assert.Equal(t, []string{strconv.Itoa(failedIndex + 1)}, stream.trailer.Get(utils.LLMAttemptsTrailer))
+	assert.Equal(t, []string{strings.Join(failed, ",")}, stream.trailer.Get(utils.LLMFailedModelsTrailer))
+
+	t.Run("first model succeeds", func(t *testing.T) {
+		fake.generateContent = func(context.Context, string, []*genai.Content) (*genai.GenerateContentResponse, error) {
+			return makeSuccessResp("Summary.", 200), nil
+		}
+		stream := &trailerStream{}
+		_, err := server.Summarize(grpc.NewContextWithServerTransportStream(context.Background(), stream), req)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"1"}, stream.trailer.Get(utils.LLMAttemptsTrailer))
+		assert.Equal(t, []string{""}, stream.trailer.Get(utils.LLMFailedModelsTrailer))
+	})
+
+	t.Run("every model fails", func(t *testing.T) {
+		fake.generateContent = func(context.Context, string, []*genai.Content) (*genai.GenerateContentResponse, error) {
+			return nil, fmt.Errorf("model overloaded")
+		}
+		stream := &trailerStream{}
+		_, err := server.Summarize(grpc.NewContextWithServerTransportStream(context.Background(), stream), req)
+		require.Error(t, err)
+		assert.Equal(t, []string{strconv.Itoa(len(llmModelPriority))}, stream.trailer.Get(utils.LLMAttemptsTrailer))
+	})
+}
+
+func TestE2E_Summarize_UnknownModel(t *testing.T) {
+	originalKey := *geminiAPIKey
+	defer func() { *geminiAPIKey = originalKey }()
+
+	req := &pb.LLMSummaryRequest{
+		ModelFamily: pb.ModelFamily_MODEL_FAMILY_GEMINI,
+		Model:       pb.Model(9999),
+		Text:        "Test content",
+	}
+
+	t.Run("strict by default", func(t *testing.T) {
+		fake := &fakeGeminiClient{}
+		server := setupTestServer(fake, 3000000)
+
+		_, err := server.Summarize(context.Background(), req)
+		require.Error(t, err)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		assert.Contains(t, err.Error(), "unsupported model")
+		assert.Equal(t, 0, fake.generateContentCalls)
+	})
+
+	t.Run("falls back to the default priority", func(t *testing.T) {
+		fake := &fakeGeminiClient{}
+		server := setupTestServer(fake, 3000000)
+		server.unknownModelFallback = true
+
+		resp, err := server.Summarize(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, llmModelPriority[0], resp.Model)
+		assert.Equal(t, llmModelNames[llmModelPriority[0]], fake.lastModel)
+	})
+}
+
 func TestE2E_Summarize_AllModelsFail(t *testing.T) {
 	originalKey := *geminiAPIKey
 	defer func() { *geminiAPIKey = originalKey }()
@@ -223,6 +331,7 @@ func TestE2E_Summarize_AllModelsFail(t *testing.T) {
 	assert.Error(t, err)
 	assert.Nil(t, resp)
 	assert.Contains(t, err.Error(), "failed to generate summary")
+	assert.Equal(t, codes.Internal, status.Code(err))
 }
 
 func TestE2E_Summarize_UnsupportedModelFamily(t *testing.T) {
@@ -353,6 +462,7 @@ func TestE2E_Summarize_TokenLimitBoundary(t *testing.T) {
 			assert.Nil(t, resp)
 			assert.Contains(t,
 				err.Error(), "failed to generate summary")
+			assert.Equal(t, codes.ResourceExhausted, status.Code(err))
 		})
 	}
 }
@@ -541,6 +651,126 @@ func TestE2E_Summarize_CustomMaxTokens(t *testing.T) {
 	assert.GreaterOrEqual(t, countCalls, 2)
 }
 
+func TestE2E_Summarize_MaxTokensExceedsContextWindow(t *testing.T) {
+	originalKey := *geminiAPIKey
+	defer func() { *geminiAPIKey = originalKey }()
+
+	model := pb.Model_MODEL_GEMINI_2_5_FLASH
+	originalWindow := llmModelContextWindows[model]
+	llmModelContextWindows[model] = 1000
+	defer func() { llmModelContextWindows[model] = originalWindow }()
+
+	fake := &fakeGeminiClient{}
+	server := setupTestServer(fake, 0)
+
+	const window = 1000
+	_, err := server.Summarize(context.Background(), &pb.LLMSummaryRequest{
+		ModelFamily: pb.ModelFamily_MODEL_FAMILY_GEMINI,
+		Model:       model,
+		Prompt:      "Summarize:",
+		Text:        "Test content",
+		MaxTokens:   window + 1,
+	})
+
+	require.Error(t, err)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, err.Error(), "context window")
+	assert.Zero(t, fake.countTokensCalls, "rejected before calling the API")
+
+	_, err = server.Summarize(context.Background(), &pb.LLMSummaryRequest{
+		ModelFamily: pb.ModelFamily_MODEL_FAMILY_GEMINI,
+		MaxTokens:   -1,
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestE2E_Summarize_MaxTokensClampedToFallbackModelWindow(t *testing.T) {
+	originalKey := *geminiAPIKey
+	defer func() { *geminiAPIKey = originalKey }()
+
+	firstModel := llmModelPriority[0]
+	originalWindow := llmModelContextWindows[firstModel]
+	llmModelContextWindows[firstModel] = 1000
+	defer func() { llmModelContextWindows[firstModel] = originalWindow }()
+
+	var budgets []int32
+	fake := &fakeGeminiClient{
+		countTokens: func(
+			ctx context.Context, model string,
+			contents []*genai.Content,
+		) (*genai.CountTokensResponse, error) {
+			// One token per character, so truncation stops once the text fits the budget.
+			return &genai.CountTokensResponse{TotalTokens: int32(len(contents[0].Parts[0].Text))}, nil
+		},
+	}
+	fake.generateContent = func(
+		ctx context.Context, model string,
+		contents []*genai.Content,
+	) (*genai.GenerateContentResponse, error) {
+		budgets = append(budgets, int32(len(contents[0].Parts[0].Text)))
+		return makeSuccessResp("Clamped summary.", 10), nil
+	}
+	server := setupTestServer(fake, 0)
+
+	resp, err := server.Summarize(context.Background(), &pb.LLMSummaryRequest{
+		ModelFamily: pb.ModelFamily_MODEL_FAMILY_GEMINI,
+		Prompt:      "Summarize:",
+		Text:        strings.Repeat("x", 5000),
+		MaxTokens:   4000,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, firstModel, resp.Model)
+	require.Len(t, budgets, 1)
+	assert.LessOrEqual(t, budgets[0], int32(1000), "content should be truncated to the model's window")
+}
+
+func TestE2E_Summarize_DefaultBudgetUsesModelWindow(t *testing.T) {
+	originalKey := *geminiAPIKey
+	defer func() { *geminiAPIKey = originalKey }()
+
+	model := pb.Model_MODEL_GEMINI_2_5_FLASH
+	originalWindow := llmModelContextWindows[model]
+	defer func() { llmModelContextWindows[model] = originalWindow }()
+
+	var generated []int
+	fake := &fakeGeminiClient{
+		countTokens: func(
+			ctx context.Context, model string,
+			contents []*genai.Content,
+		) (*genai.CountTokensResponse, error) {
+			// One token per character, so truncation stops once the text fits the budget.
+			return &genai.CountTokensResponse{TotalTokens: int32(len(contents[0].Parts[0].Text))}, nil
+		},
+	}
+	fake.generateContent = func(
+		ctx context.Context, model string,
+		contents []*genai.Content,
+	) (*genai.GenerateContentResponse, error) {
+		generated = append(generated, len(contents[0].Parts[0].Text))
+		return makeSuccessResp("Summary.", 10), nil
+	}
+	server := setupTestServer(fake, 0)
+	req := &pb.LLMSummaryRequest{
+		ModelFamily: pb.ModelFamily_MODEL_FAMILY_GEMINI,
+		Model:       model,
+		Prompt:      "Summarize:",
+		Text:        strings.Repeat("x", int(tokenLimit)+1000),
+	}
+
+	llmModelContextWindows[model] = 2 * tokenLimit
+	_, err := server.Summarize(context.Background(), req)
+	require.NoError(t, err)
+
+	llmModelContextWindows[model] = 1000
+	_, err = server.Summarize(context.Background(), req)
+	require.NoError(t, err)
+
+	require.Len(t, generated, 2)
+	assert.Greater(t, generated[0], int(tokenLimit), "a larger window keeps content beyond tokenLimit")
+	assert.LessOrEqual(t, generated[1], 1000, "a smaller window truncates without an explicit max_tokens")
+}
+
 // --- E2E Tests: Token Sliding Window ---
 
 func TestE2E_Summarize_SlidingWindowExpiry(t *testing.T) {
@@ -874,3 +1104,262 @@ func TestE2E_Summarize_TokensNotRecordedOnFailure(t *testing.T) {
 	// Tokens should NOT be recorded since generation failed
 	assert.Equal(t, int32(0), server.tracker.CurrentUsage())
 }
+
+// --- E2E Tests: Concurrency Limit ---
+
+func TestE2E_Summarize_ConcurrencyLimitQueuesExtraCalls(t *testing.T) {
+	originalKey := *geminiAPIKey
+	defer func() { *geminiAPIKey = originalKey }()
+
+	const limit = 2
+	var active, maxActive int32
+	fake := &fakeGeminiClient{
+		generateContent: func(
+			ctx context.Context, model string,
+			contents []*genai.Content,
+		) (*genai.GenerateContentResponse, error) {
+			current := atomic.AddInt32(&active, 1)
+			for {
+				observed := atomic.LoadInt32(&maxActive)
+				if current <= observed || atomic.CompareAndSwapInt32(&maxActive, observed, current) {
+					break
+				}
+			}
+			time.Sleep(30 * time.Millisecond)
+			atomic.AddInt32(&active, -1)
+			return makeSuccessResp("Queued summary.", 10), nil
+		},
+	}
+	server := setupTestServer(fake, 0)
+	server.concurrency = utils.NewSemaphore(limit)
+
+	req := &pb.LLMSummaryRequest{
+		ModelFamily: pb.ModelFamily_MODEL_FAMILY_GEMINI,
+		Model:       pb.Model_MODEL_GEMINI_2_5_FLASH_LITE,
+		Prompt:      "Summarize:",
+		Text:        "Test",
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 6)
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := server.Summarize(context.Background(), req)
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+	assert.LessOrEqual(t, atomic.LoadInt32(&maxActive), int32(limit))
+	assert.Equal(t, 6, fake.generateContentCalls)
+}
+
+func TestE2E_SummaryByGemini_ConcurrencyLimitRespectsContext(t *testing.T) {
+	originalKey := *geminiAPIKey
+	defer func() { *geminiAPIKey = originalKey }()
+
+	fake := &fakeGeminiClient{}
+	server := setupTestServer(fake, 0)
+	server.concurrency = utils.NewSemaphore(1)
+
+	// Hold the only slot so the call has to wait.
+	require.NoError(t, server.concurrency.Acquire(context.Background()))
+	defer server.concurrency.Release()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	summary, err := server.summaryByGemini(
+		ctx, "prompt", "content", pb.Model_MODEL_GEMINI_2_5_FLASH_LITE, tokenLimit,
+	)
+	require.Error(t, err)
+	assert.Empty(t, summary)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Equal(t, 0, fake.countTokensCalls)
+}
+
+func TestE2E_Summarize_PreservesValidationStatus(t *testing.T) {
+	originalKey := *geminiAPIKey
+	defer func() { *geminiAPIKey = originalKey }()
+
+	fake := &fakeGeminiClient{}
+	server := setupTestServer(fake, 0)
+	*geminiAPIKey = ""
+
+	resp, err := server.Summarize(context.Background(), &pb.LLMSummaryRequest{
+		ModelFamily: pb.ModelFamily_MODEL_FAMILY_GEMINI,
+		Prompt:      "Summarize:",
+		Text:        "Test content",
+	})
+
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, err.Error(), "gemini-api-key is empty")
+	assert.Zero(t, fake.countTokensCalls)
+}
+
+func TestE2E_Summarize_TokenLimitSkipsFallbackModels(t *testing.T) {
+	originalKey := *geminiAPIKey
+	defer func() { *geminiAPIKey = originalKey }()
+
+	fake := &fakeGeminiClient{
+		countTokens: func(
+			ctx context.Context, model string,
+			contents []*genai.Content,
+		) (*genai.CountTokensResponse, error) {
+			return &genai.CountTokensResponse{TotalTokens: 200}, nil
+		},
+	}
+	server := setupTestServer(fake, 100)
+
+	_, err := server.Summarize(context.Background(), &pb.LLMSummaryRequest{
+		ModelFamily: pb.ModelFamily_MODEL_FAMILY_GEMINI,
+		Prompt:      "Summarize:",
+		Text:        "Test content",
+	})
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, 1, fake.countTokensCalls, "the shared budget fails every model, so no fallback is tried")
+	assert.Zero(t, fake.generateContentCalls)
+}
+
+func TestE2E_Summarize_TruncatesOverLongSummary(t *testing.T) {
+	originalKey := *geminiAPIKey
+	defer func() { *geminiAPIKey = originalKey }()
+
+	fake := &fakeGeminiClient{}
+	server := setupTestServer(fake, 3000000)
+	server.maxSummaryRunes = 10
+
+	resp, err := server.Summarize(context.Background(), &pb.LLMSummaryRequest{
+		ModelFamily: pb.ModelFamily_MODEL_FAMILY_GEMINI,
+		Text:        "Hello, this is a test email with some content.",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "This is a…", resp.Summary)
+}
+
+func TestE2E_Summarize_SkipsTokenCountForSmallInput(t *testing.T) {
+	originalKey := *geminiAPIKey
+	defer func() { *geminiAPIKey = originalKey }()
+
+	req := &pb.LLMSummaryRequest{
+		ModelFamily: pb.ModelFamily_MODEL_FAMILY_GEMINI,
+		Prompt:      "Summarize:",
+		Text:        "Lunch at noon?",
+	}
+
+	t.Run("small input goes straight to generation", func(t *testing.T) {
+		fake := &fakeGeminiClient{}
+		server := setupTestServer(fake, 3000000)
+		server.skipTokenCountBelowChars = 1000
+
+		resp, err := server.Summarize(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, "This is a test summary.", resp.Summary)
+		assert.Zero(t, fake.countTokensCalls)
+		assert.Equal(t, 1, fake.generateContentCalls)
+		assert.Equal(t, int32(150), server.tracker.CurrentUsage(), "usage comes from the generation metadata")
+	})
+
+	t.Run("input at the threshold is counted", func(t *testing.T) {
+		fake := &fakeGeminiClient{}
+		server := setupTestServer(fake, 3000000)
+		server.skipTokenCountBelowChars = len(req.Prompt + "\n" + req.Text)
+
+		_, err := server.Summarize(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, 1, fake.countTokensCalls)
+	})
+
+	t.Run("disabled by default", func(t *testing.T) {
+		fake := &fakeGeminiClient{}
+		server := setupTestServer(fake, 3000000)
+
+		_, err := server.Summarize(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, 1, fake.countTokensCalls)
+	})
+
+	t.Run("estimate still enforces the daily limit", func(t *testing.T) {
+		fake := &fakeGeminiClient{}
+		server := setupTestServer(fake, 10)
+		server.skipTokenCountBelowChars = 1000
+
+		_, err := server.Summarize(context.Background(), req)
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+		assert.Zero(t, fake.countTokensCalls)
+		assert.Zero(t, fake.generateContentCalls)
+	})
+}
+
+func TestE2E_Summarize_RateLimitedModel(t *testing.T) {
+	originalKey := *geminiAPIKey
+	defer func() { *geminiAPIKey = originalKey }()
+
+	// newFake rate limits the first GenerateContent call with a 50ms retry hint.
+	newFake := func() (*fakeGeminiClient, *[]string) {
+		var models []string
+		fake := &fakeGeminiClient{}
+		fake.generateContent = func(
+			ctx context.Context, model string,
+			contents []*genai.Content,
+		) (*genai.GenerateContentResponse, error) {
+			models = append(models, model)
+			if len(models) == 1 {
+				return nil, rateLimitError("0.05s")
+			}
+			return makeSuccessResp("Summary after rate limit.", 10), nil
+		}
+		return fake, &models
+	}
+	req := &pb.LLMSummaryRequest{
+		ModelFamily: pb.ModelFamily_MODEL_FAMILY_GEMINI,
+		Prompt:      "Summarize:",
+		Text:        "Test content",
+	}
+
+	t.Run("waits out a short suggested delay on the same model", func(t *testing.T) {
+		fake, models := newFake()
+		server := setupTestServer(fake, 0)
+		server.rateLimitMaxWait = time.Second
+
+		start := time.Now()
+		resp, err := server.Summarize(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, llmModelPriority[0], resp.Model)
+		first := llmModelNames[llmModelPriority[0]]
+		assert.Equal(t, []string{first, first}, *models)
+		assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+	})
+
+	t.Run("moves on immediately when waiting is disabled", func(t *testing.T) {
+		fake, models := newFake()
+		server := setupTestServer(fake, 0)
+
+		start := time.Now()
+		resp, err := server.Summarize(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, llmModelPriority[1], resp.Model)
+		assert.Equal(t, []string{llmModelNames[llmModelPriority[0]], llmModelNames[llmModelPriority[1]]}, *models)
+		assert.Less(t, time.Since(start), time.Second, "no flat pause after a rate limit")
+	})
+
+	t.Run("moves on when the suggested delay is too long", func(t *testing.T) {
+		fake, models := newFake()
+		server := setupTestServer(fake, 0)
+		server.rateLimitMaxWait = 10 * time.Millisecond
+
+		resp, err := server.Summarize(context.Background(), req)
+		require.NoError(t, err)
+		assert.Equal(t, llmModelPriority[1], resp.Model)
+		assert.Len(t, *models, 2)
+	})
+}
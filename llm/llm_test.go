@@ -2,10 +2,15 @@ package main
 
 import (
 	"context"
+	"os"
+	"path/filepath"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	pb "github.com/ziyixi/protos/go/todofy"
+	"google.golang.org/genai"
 )
 
 func TestNormalizeDailyTokenLimit(t *testing.T) {
@@ -82,7 +87,7 @@ func TestLLMServer_SummaryInternal(t *testing.T) {
 		server := &llmServer{}
 
 		// Test with an unsupported model (using UNSPECIFIED as invalid)
-		summary, model, err := server.summaryInternal(
+		summary, model, _, err := server.summaryInternal(
 			context.Background(),
 			pb.ModelFamily_MODEL_FAMILY_GEMINI,
 			"prompt",
@@ -103,7 +108,7 @@ func TestLLMServer_SummaryInternal(t *testing.T) {
 		// Test with valid models that exist in our mapping
 		// This will fail at API call but should pass model validation
 		for _, model := range llmModelPriority[:1] { // Just test first model
-			summary, returnedModel, err := server.summaryInternal(
+			summary, returnedModel, _, err := server.summaryInternal(
 				context.Background(),
 				pb.ModelFamily_MODEL_FAMILY_GEMINI,
 				"prompt",
@@ -251,3 +256,134 @@ func TestTokenLimitHandling(t *testing.T) {
 		assert.NotContains(t, err.Error(), "unsupported model family")
 	})
 }
+
+func TestResolveAPIKeyFlags(t *testing.T) {
+	originalKey, originalFile := *geminiAPIKey, *geminiAPIKeyFile
+	t.Cleanup(func() {
+		*geminiAPIKey, *geminiAPIKeyFile = originalKey, originalFile
+	})
+
+	path := filepath.Join(t.TempDir(), "gemini-key")
+	require.NoError(t, os.WriteFile(path, []byte("file-key\n"), 0o600))
+
+	*geminiAPIKey, *geminiAPIKeyFile = "", path
+	require.NoError(t, resolveAPIKeyFlags())
+	assert.Equal(t, "file-key", *geminiAPIKey)
+
+	*geminiAPIKey, *geminiAPIKeyFile = "inline-key", path
+	require.NoError(t, resolveAPIKeyFlags())
+	assert.Equal(t, "inline-key", *geminiAPIKey)
+
+	*geminiAPIKey, *geminiAPIKeyFile = "", filepath.Join(t.TempDir(), "missing")
+	assert.Error(t, resolveAPIKeyFlags())
+}
+
+func TestGeminiClientConfig(t *testing.T) {
+	t.Run("gemini backend uses the API key", func(t *testing.T) {
+		config, err := geminiClientConfig(geminiBackendGeminiAPI, "key", "project", "us-central1")
+		require.NoError(t, err)
+		assert.Equal(t, genai.BackendGeminiAPI, config.Backend)
+		assert.Equal(t, "key", config.APIKey)
+		assert.Empty(t, config.Project)
+		assert.Empty(t, config.Location)
+	})
+
+	t.Run("vertex backend uses project and location", func(t *testing.T) {
+		config, err := geminiClientConfig(geminiBackendVertexAI, "key", "project", "us-central1")
+		require.NoError(t, err)
+		assert.Equal(t, genai.BackendVertexAI, config.Backend)
+		assert.Empty(t, config.APIKey)
+		assert.Equal(t, "project", config.Project)
+		assert.Equal(t, "us-central1", config.Location)
+	})
+
+	t.Run("vertex backend requires project and location", func(t *testing.T) {
+		_, err := geminiClientConfig(geminiBackendVertexAI, "", "project", "")
+		assert.ErrorContains(t, err, "--gcp-location")
+		_, err = geminiClientConfig(geminiBackendVertexAI, "", "", "us-central1")
+		assert.ErrorContains(t, err, "--gcp-project")
+	})
+
+	t.Run("unknown backend returns error", func(t *testing.T) {
+		_, err := geminiClientConfig("openai", "key", "", "")
+		assert.ErrorContains(t, err, "unsupported gemini backend")
+	})
+}
+
+func TestSummaryByGemini_VertexBackendAllowsEmptyAPIKey(t *testing.T) {
+	originalKey, originalBackend := *geminiAPIKey, *geminiBackend
+	t.Cleanup(func() {
+		*geminiAPIKey, *geminiBackend = originalKey, originalBackend
+	})
+
+	fake := &fakeGeminiClient{}
+	server := setupTestServer(fake, 3000000)
+	*geminiAPIKey, *geminiBackend = "", geminiBackendVertexAI
+
+	summary, err := server.summaryByGemini(
+		context.Background(), "prompt", "content", pb.Model_MODEL_GEMINI_2_5_FLASH_LITE, tokenLimit,
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "This is a test summary.", summary)
+	assert.Equal(t, 1, fake.generateContentCalls)
+}
+
+func TestTruncateSummary(t *testing.T) {
+	tests := []struct {
+		name     string
+		summary  string
+		maxRunes int
+		want     string
+	}{
+		{"disabled", "A long summary.", 0, "A long summary."},
+		{"under cap", "Short.", 10, "Short."},
+		{"exactly at cap", "Exact fit!", 10, "Exact fit!"},
+		{"over cap", "Reply to the budget email", 10, "Reply to…"},
+		{"trailing space dropped", "Reply to  the budget email", 10, "Reply to…"},
+		{"multibyte runes", "回复预算邮件并确认会议时间", 5, "回复预算…"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateSummary(tt.summary, tt.maxRunes)
+			assert.Equal(t, tt.want, got)
+			assert.True(t, utf8.ValidString(got))
+			if tt.maxRunes > 0 {
+				assert.LessOrEqual(t, utf8.RuneCountInString(got), tt.maxRunes)
+			}
+		})
+	}
+}
+
+func TestEstimateSmallInputTokens(t *testing.T) {
+	server := &llmServer{skipTokenCountBelowChars: 10}
+
+	resp, skipped := server.estimateSmallInputTokens("short", 100)
+	require.True(t, skipped)
+	assert.Equal(t, int32(5), resp.TotalTokens)
+
+	_, skipped = server.estimateSmallInputTokens("long enough", 100)
+	assert.False(t, skipped, "inputs at or over the threshold are counted")
+
+	_, skipped = server.estimateSmallInputTokens("short", 4)
+	assert.False(t, skipped, "inputs that might need truncation are counted")
+
+	_, skipped = (&llmServer{}).estimateSmallInputTokens("", 100)
+	assert.False(t, skipped, "a zero threshold always counts")
+}
+
+func TestModelTokenBudget(t *testing.T) {
+	const small, large = pb.Model_MODEL_GEMINI_2_5_FLASH_LITE, pb.Model_MODEL_GEMINI_2_5_PRO
+	originalSmall, originalLarge := llmModelContextWindows[small], llmModelContextWindows[large]
+	llmModelContextWindows[small] = 32768
+	llmModelContextWindows[large] = 2 * tokenLimit
+	t.Cleanup(func() {
+		llmModelContextWindows[small] = originalSmall
+		llmModelContextWindows[large] = originalLarge
+	})
+
+	assert.Equal(t, int32(32768), modelTokenBudget(small, 0), "unset budget uses the model window")
+	assert.Equal(t, 2*tokenLimit, modelTokenBudget(large, 0), "large windows are not capped at tokenLimit")
+	assert.Equal(t, int32(1000), modelTokenBudget(small, 1000))
+	assert.Equal(t, int32(32768), modelTokenBudget(small, 50000), "requests are clamped to the window")
+	assert.Equal(t, tokenLimit, modelTokenBudget(pb.Model_MODEL_UNSPECIFIED, 0), "unknown models use tokenLimit")
+}
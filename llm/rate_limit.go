@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// retryInfoType is the @type of the google.rpc.RetryInfo detail Gemini attaches to 429 errors.
+const retryInfoType = "type.googleapis.com/google.rpc.RetryInfo"
+
+// geminiRateLimitDelay reports whether err is a Gemini 429 and returns the retry delay it
+// suggests, or 0 when the error carries no RetryInfo.
+func geminiRateLimitDelay(err error) (time.Duration, bool) {
+	var apiErr genai.APIError
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusTooManyRequests {
+		return 0, false
+	}
+	for _, detail := range apiErr.Details {
+		if typ, _ := detail["@type"].(string); !strings.EqualFold(typ, retryInfoType) {
+			continue
+		}
+		// RetryInfo.retryDelay is a protobuf Duration, which JSON encodes as e.g. "13s" or "1.5s"
+		raw, _ := detail["retryDelay"].(string)
+		if delay, err := time.ParseDuration(raw); err == nil && delay > 0 {
+			return delay, true
+		}
+	}
+	return 0, true
+}
+
+// sleepContext waits for d, returning early with the context error if ctx is done first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genai"
+)
+
+func rateLimitError(retryDelay string) error {
+	apiErr := genai.APIError{Code: 429, Status: "RESOURCE_EXHAUSTED", Message: "quota exceeded"}
+	if retryDelay != "" {
+		apiErr.Details = []map[string]any{
+			{"@type": "type.googleapis.com/google.rpc.QuotaFailure"},
+			{"@type": retryInfoType, "retryDelay": retryDelay},
+		}
+	}
+	return apiErr
+}
+
+func TestGeminiRateLimitDelay(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantDelay   time.Duration
+		rateLimited bool
+	}{
+		{"retry info", rateLimitError("13s"), 13 * time.Second, true},
+		{"fractional delay", rateLimitError("1.5s"), 1500 * time.Millisecond, true},
+		{"wrapped", fmt.Errorf("failed to generate content: %w", rateLimitError("2s")), 2 * time.Second, true},
+		{"no retry info", rateLimitError(""), 0, true},
+		{"unparsable delay", rateLimitError("soon"), 0, true},
+		{"other api error", genai.APIError{Code: 500}, 0, false},
+		{"plain error", errors.New("boom"), 0, false},
+		{"nil", nil, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			delay, rateLimited := geminiRateLimitDelay(tt.err)
+			assert.Equal(t, tt.wantDelay, delay)
+			assert.Equal(t, tt.rateLimited, rateLimited)
+		})
+	}
+}
+
+func TestSleepContext(t *testing.T) {
+	assert.NoError(t, sleepContext(context.Background(), time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.ErrorIs(t, sleepContext(ctx, time.Hour), context.Canceled)
+}
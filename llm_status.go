@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/ziyixi/todofy/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// llmErrorHTTPStatus maps an LLM Summarize failure to the HTTP status handlers answer with:
+// 429 when the daily token budget is spent, 400 for requests the LLM service rejected, else 500.
+func llmErrorHTTPStatus(err error) int {
+	switch status.Code(err) {
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.InvalidArgument:
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// logLLMFallback warns when the LLM service had to fall back to a later model for a Summarize call,
+// as reported in the trailer it sets.
+func logLLMFallback(endpoint string, trailer metadata.MD) {
+	failed := trailer.Get(utils.LLMFailedModelsTrailer)
+	if len(failed) == 0 || failed[0] == "" {
+		return
+	}
+	attempts := "unknown"
+	if values := trailer.Get(utils.LLMAttemptsTrailer); len(values) > 0 {
+		attempts = values[0]
+	}
+	log.Warningf("LLM call for %s took %s attempts; failed models: %s", endpoint, attempts, failed[0])
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ziyixi/todofy/utils"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestLLMErrorHTTPStatus(t *testing.T) {
+	assert.Equal(t, http.StatusTooManyRequests, llmErrorHTTPStatus(status.Error(codes.ResourceExhausted, "limit")))
+	assert.Equal(t, http.StatusBadRequest, llmErrorHTTPStatus(status.Error(codes.InvalidArgument, "bad")))
+	assert.Equal(t, http.StatusInternalServerError, llmErrorHTTPStatus(status.Error(codes.Internal, "failed")))
+}
+
+func TestLogLLMFallback(t *testing.T) {
+	hook := logtest.NewLocal(log)
+	t.Cleanup(func() { log.ReplaceHooks(make(logrus.LevelHooks)) })
+
+	logLLMFallback("summary", nil)
+	logLLMFallback("summary", metadata.Pairs(utils.LLMAttemptsTrailer, "1", utils.LLMFailedModelsTrailer, ""))
+	assert.Empty(t, hook.AllEntries(), "no fallback, nothing to log")
+
+	logLLMFallback("summary", metadata.Pairs(
+		utils.LLMAttemptsTrailer, "2", utils.LLMFailedModelsTrailer, "gemini-2.5-flash-lite",
+	))
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, logrus.WarnLevel, entry.Level)
+	assert.Equal(t, "LLM call for summary took 2 attempts; failed models: gemini-2.5-flash-lite", entry.Message)
+}
@@ -5,7 +5,11 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"net/http"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -28,25 +32,63 @@ func initLogger() {
 
 // Config holds all configuration parameters
 type Config struct {
-	AllowedUsers       string
-	DataBasePath       string
-	Port               int
-	HealthCheckTimeout int
-	LLMAddr            string
-	TodoAddr           string
-	DependencyAddr     string
-	DatabaseAddr       string
+	AllowedUsers             string
+	DataBasePath             string
+	DatabaseType             string
+	Port                     int
+	HealthCheckTimeout       int
+	HealthCheckTimeouts      string
+	HealthCheckInterval      int
+	HealthCheckFailFast      bool
+	ConnectTimeout           int
+	ConnectTimeouts          string
+	DatabaseSetupAttempts    int
+	HTTPReadTimeout          int
+	HTTPWriteTimeout         int
+	HTTPIdleTimeout          int
+	LLMAddr                  string
+	TodoAddr                 string
+	DependencyAddr           string
+	DatabaseAddr             string
+	GRPCAuthToken            string
+	SystemEmailSender        string
+	AllowedSenderDomains     string
+	PrioritySenders          string
+	RecommendationWebhook    string
+	RecommendationMaxEntries int
+	EntryLimitMaxAgeHours    int
+	SummaryCron              string
+	SummaryWebhook           string
+	SummaryEmptyMessage      string
+	SummarySkipEmpty         bool
+	SummarySplitter          string
+	SummaryCategories        string
+	SummaryEntryHeader       string
+	LLMEntriesJSON           bool
+	PromptPrefix             string
+	PromptSuffix             string
+	StripEmailImages         bool
+	FlattenEmailTables       bool
+	AnnotateTaskModel        bool
+	DeadLetterDir            string
+	GinMode                  string
+	APIRateLimit             int
+	ShowVersion              bool
 }
 
 var (
 	config    Config
 	GitCommit string // Will be set by Bazel at build time
+	BuildDate string // Will be set via -ldflags at build time
 )
 
+// promptPrefix and promptSuffix bracket every built-in LLM prompt; set from --prompt-prefix and --prompt-suffix.
+var promptPrefix, promptSuffix string
+
 type startupClients interface {
 	Close()
 	WaitForHealthy(context.Context) error
-	SetUpDataBase(path string) error
+	SetUpDataBase(path string, setup databaseSetup) error
 	ServiceNames() []string
 }
 
@@ -64,11 +106,14 @@ var (
 		if !ok {
 			return nil, fmt.Errorf("unexpected grpc clients type %T", clients)
 		}
-		return setupRouter(allowedUsers, grpcClients), nil
+		return &httpServerRunner{handler: setupRouter(allowedUsers, grpcClients), timeouts: serverTimeouts}, nil
 	}
 	runApplication = run
 )
 
+// versionOutput is where --version writes; overridden in tests.
+var versionOutput io.Writer = os.Stdout
+
 // initFlags initializes command line flags
 func initFlags() {
 	initFlagsWithFlagSet(flag.CommandLine, &config)
@@ -78,47 +123,184 @@ func initFlagsWithFlagSet(fs *flag.FlagSet, cfg *Config) {
 	fs.StringVar(&cfg.AllowedUsers, "allowed-users", "",
 		"Comma-separated list of allowed users in the format 'username:password'")
 	fs.StringVar(&cfg.DataBasePath, "database-path", "", "Path to the SQLite database file")
+	fs.StringVar(&cfg.DatabaseType, "database-type", "sqlite",
+		"Database backend sent with every database request, e.g. 'sqlite'")
 	fs.IntVar(&cfg.Port, "port", 8080, "Port to run the server on")
 	fs.IntVar(&cfg.HealthCheckTimeout, "health-check-timeout", 10, "Timeout for health check in seconds")
+	fs.StringVar(&cfg.HealthCheckTimeouts, "health-check-timeouts", "",
+		"Comma-separated per-service health check timeouts in seconds, e.g. 'database=30' "+
+			"(others use health-check-timeout)")
+	fs.IntVar(&cfg.HealthCheckInterval, "health-check-interval-ms", 500,
+		"Interval between health check probes in milliseconds")
+	fs.BoolVar(&cfg.HealthCheckFailFast, "health-check-failfast", false,
+		"Fail startup at once when a service's health check can't succeed by retrying "+
+			"(no health service, rejected auth token or unknown service)")
+	fs.IntVar(&cfg.ConnectTimeout, "connect-timeout", 0,
+		"Seconds to wait on startup for each backend connection before failing (0 = connect lazily)")
+	fs.StringVar(&cfg.ConnectTimeouts, "connect-timeouts", "",
+		"Comma-separated per-service connect timeouts in seconds, e.g. 'database=30' (others use connect-timeout)")
+	fs.IntVar(&cfg.DatabaseSetupAttempts, "database-setup-attempts", 3,
+		"Attempts at creating the database on startup before giving up (retried with doubling backoff)")
+	fs.IntVar(&cfg.HTTPReadTimeout, "http-read-timeout", 30,
+		"Seconds a client may take to send a full request, body included (0 = no limit)")
+	fs.IntVar(&cfg.HTTPWriteTimeout, "http-write-timeout", 300,
+		"Seconds from the end of a request's headers until its response must be written (0 = no limit)")
+	fs.IntVar(&cfg.HTTPIdleTimeout, "http-idle-timeout", 120,
+		"Seconds an idle keep-alive connection stays open (0 = use http-read-timeout)")
+	fs.IntVar(&cfg.APIRateLimit, "api-rate-limit-per-minute", 0,
+		"Requests per minute allowed across all /api routes, including the LLM-backed summary and "+
+			"recommendation (0 disables; /api/v1 also keeps RATE_LIMIT_REQUESTS_PER_MINUTE)")
+	fs.StringVar(&cfg.GinMode, "gin-mode", gin.ReleaseMode, "Gin mode: release, debug (logs routes) or test")
+	fs.BoolVar(&cfg.ShowVersion, "version", false, "Print version information and exit")
 
 	// GRPC addresses for the services
 	fs.StringVar(&cfg.LLMAddr, "llm-addr", ":50051", "Address of the LLM server")
 	fs.StringVar(&cfg.TodoAddr, "todo-addr", ":50052", "Address of the Todo server")
 	fs.StringVar(&cfg.DependencyAddr, "dependency-addr", "", "Address of the Dependency server (defaults to todo-addr)")
 	fs.StringVar(&cfg.DatabaseAddr, "database-addr", ":50053", "Address of the Database server")
+	fs.StringVar(&cfg.SystemEmailSender, "system-email-sender", "",
+		"Address todofy's own mails are sent from; inbound mail from it is skipped like system-prefixed subjects")
+	fs.StringVar(&cfg.AllowedSenderDomains, "allowed-sender-domains", "",
+		"Comma-separated sender domains allowed to create tasks (empty allows all)")
+	fs.StringVar(&cfg.PrioritySenders, "priority-senders", "",
+		"Comma-separated sender addresses whose tasks recommendations rank above comparable ones")
+	fs.StringVar(&cfg.RecommendationWebhook, "recommendation-webhook", "",
+		"URL that /api/recommendation?notify=true POSTs the ranked tasks to")
+	fs.IntVar(&cfg.RecommendationMaxEntries, "recommendation-max-entries", 0,
+		"Rank only the N most recent tasks in /api/recommendation, dropping older ones (0 = all)")
+	fs.IntVar(&cfg.EntryLimitMaxAgeHours, "entry-limit-max-age-hours", defaultEntryLimitMaxAgeHours,
+		"How many hours back ?limit=N looks for the most recent tasks")
+	fs.StringVar(&cfg.SummaryCron, "summary-cron", "",
+		"Local time of day (HH:MM, 24-hour) to generate the daily summary internally (empty disables)")
+	fs.StringVar(&cfg.SummaryWebhook, "summary-webhook", "",
+		"URL each scheduled summary is POSTed to as JSON (required with --summary-cron)")
+	fs.StringVar(&cfg.SummaryEmptyMessage, "summary-empty-message", defaultSummaryEmptyMessage,
+		"Summary text returned when no tasks were received in the summary window")
+	fs.BoolVar(&cfg.SummarySkipEmpty, "summary-skip-empty", false,
+		"Return 204 from /api/summary (and skip scheduled summaries) when there are no tasks")
+	fs.StringVar(&cfg.SummarySplitter, "summary-splitter", defaultEntrySplitter,
+		"Line separating stored tasks in the content sent to the LLM for summaries and recommendations")
+	fs.StringVar(&cfg.SummaryCategories, "summary-categories", "",
+		"Comma-separated categories the daily summary groups tasks into, least important last (empty = built-in)")
+	fs.StringVar(&cfg.SummaryEntryHeader, "summary-entry-header", "",
+		"Go template (.Index, .Date, .Subject) for each task's header line in LLM input (empty = built-in)")
+	fs.BoolVar(&cfg.LLMEntriesJSON, "llm-entries-json", false,
+		"Send summary and recommendation tasks to the LLM as a JSON array instead of splitter-separated text")
+	fs.StringVar(&cfg.PromptPrefix, "prompt-prefix", "",
+		"Instruction prepended to every built-in LLM prompt (email, summary and recommendation)")
+	fs.StringVar(&cfg.PromptSuffix, "prompt-suffix", "",
+		"Instruction appended to every built-in LLM prompt, e.g. \"Always mention deadlines\"")
+	fs.BoolVar(&cfg.StripEmailImages, "strip-email-images", false,
+		"Drop images from inbound email HTML instead of converting them to markdown image links")
+	fs.BoolVar(&cfg.FlattenEmailTables, "flatten-email-tables", false,
+		"Render each table row of inbound email HTML as one line of \" | \"-separated cells")
+	fs.BoolVar(&cfg.AnnotateTaskModel, "annotate-task-model", false,
+		"Append \"— summarized by <model>\" to each created task's description")
+	fs.StringVar(&cfg.DeadLetterDir, "dead-letter-dir", "",
+		"Directory storing update_todo requests whose backend steps fail with 429 or 5xx, for replay (empty disables)")
+	fs.StringVar(&cfg.GRPCAuthToken, "grpc-auth-token", "",
+		"Shared secret sent as a bearer token to backend services (empty disables auth)")
 }
 
 func buildServiceConfigs(cfg Config) []ServiceConfig {
-	return []ServiceConfig{
+	configs := []ServiceConfig{
 		{
-			name: "llm",
-			addr: cfg.LLMAddr,
+			name:      "llm",
+			addr:      cfg.LLMAddr,
+			authToken: cfg.GRPCAuthToken,
 			newClient: func(conn *grpc.ClientConn) any {
 				return pb.NewLLMSummaryServiceClient(conn)
 			},
 		},
 		{
-			name: "todo",
-			addr: cfg.TodoAddr,
+			name:      "todo",
+			addr:      cfg.TodoAddr,
+			authToken: cfg.GRPCAuthToken,
 			newClient: func(conn *grpc.ClientConn) any {
 				return pb.NewTodoServiceClient(conn)
 			},
 		},
 		{
-			name: "database",
-			addr: cfg.DatabaseAddr,
+			name:      "database",
+			addr:      cfg.DatabaseAddr,
+			authToken: cfg.GRPCAuthToken,
 			newClient: func(conn *grpc.ClientConn) any {
 				return pb.NewDataBaseServiceClient(conn)
 			},
 		},
 		{
-			name: "dependency",
-			addr: cfg.DependencyAddr,
+			name:      "dependency",
+			addr:      cfg.DependencyAddr,
+			authToken: cfg.GRPCAuthToken,
 			newClient: func(conn *grpc.ClientConn) any {
 				return pb.NewDependencyServiceClient(conn)
 			},
 		},
 	}
+
+	// run() has already validated the per-service timeouts, so parse errors cannot happen here
+	timeouts, _ := parseServiceTimeouts(cfg.HealthCheckTimeouts)
+	connectTimeouts, _ := parseServiceTimeouts(cfg.ConnectTimeouts)
+	for i := range configs {
+		configs[i].healthTimeout = time.Duration(cfg.HealthCheckTimeout) * time.Second
+		if timeout, ok := timeouts[configs[i].name]; ok {
+			configs[i].healthTimeout = timeout
+		}
+		configs[i].connectTimeout = time.Duration(cfg.ConnectTimeout) * time.Second
+		if timeout, ok := connectTimeouts[configs[i].name]; ok {
+			configs[i].connectTimeout = timeout
+		}
+		configs[i].healthInterval = time.Duration(cfg.HealthCheckInterval) * time.Millisecond
+		configs[i].healthFailFast = cfg.HealthCheckFailFast
+	}
+	return configs
+}
+
+// parseServiceTimeouts parses "name=seconds" pairs such as "database=30,llm=5".
+func parseServiceTimeouts(value string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, secondsStr, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid entry %q, expected 'service=seconds'", entry)
+		}
+		seconds, err := strconv.Atoi(strings.TrimSpace(secondsStr))
+		if err != nil || seconds <= 0 {
+			return nil, fmt.Errorf("invalid timeout for %s: %q", name, secondsStr)
+		}
+		timeouts[name] = time.Duration(seconds) * time.Second
+	}
+	return timeouts, nil
+}
+
+// parseKnownServiceTimeouts parses value like parseServiceTimeouts and rejects services
+// that buildServiceConfigs does not configure.
+func parseKnownServiceTimeouts(cfg Config, value string) (map[string]time.Duration, error) {
+	timeouts, err := parseServiceTimeouts(value)
+	if err != nil {
+		return nil, err
+	}
+	for name := range timeouts {
+		if !slices.ContainsFunc(buildServiceConfigs(cfg), func(sc ServiceConfig) bool { return sc.name == name }) {
+			return nil, fmt.Errorf("unknown service %q", name)
+		}
+	}
+	return timeouts, nil
+}
+
+// healthCheckDeadline is the overall startup health deadline: the global timeout,
+// extended to the longest per-service timeout so slower services get their full budget.
+func healthCheckDeadline(cfg Config, timeouts map[string]time.Duration) time.Duration {
+	deadline := time.Duration(cfg.HealthCheckTimeout) * time.Second
+	for _, timeout := range timeouts {
+		deadline = max(deadline, timeout)
+	}
+	return deadline
 }
 
 func setupGRPCClients(cfg Config) (*GRPCClients, error) {
@@ -130,10 +312,26 @@ func setupGRPCClients(cfg Config) (*GRPCClients, error) {
 	return clients, nil
 }
 
+// Router settings applied by setupRouter.
+var (
+	ginMode = gin.ReleaseMode // set from --gin-mode
+	// apiRateLimitPerMinute throttles the whole /api group; set from --api-rate-limit-per-minute (0 disables).
+	apiRateLimitPerMinute int
+)
+
 func setupRouter(allowedUsers gin.Accounts, grpcClients *GRPCClients) *gin.Engine {
-	gin.SetMode(gin.ReleaseMode)
+	gin.SetMode(ginMode)
 	app := gin.Default()
 
+	// Answer unknown routes and methods with the same JSON error shape as the handlers
+	app.HandleMethodNotAllowed = true
+	app.NoRoute(func(c *gin.Context) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "route not found", "path": c.Request.URL.Path})
+	})
+	app.NoMethod(func(c *gin.Context) {
+		c.JSON(http.StatusMethodNotAllowed, gin.H{"error": "method not allowed", "method": c.Request.Method})
+	})
+
 	// Add public health endpoint (no auth required)
 	app.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{
@@ -143,10 +341,18 @@ func setupRouter(allowedUsers gin.Accounts, grpcClients *GRPCClients) *gin.Engin
 		})
 	})
 
+	// Kubernetes-style probes, also without auth: /livez only needs the process, /readyz the backends
+	app.GET("/livez", handleLivez)
+	app.GET("/readyz", handleReadyz(newReadinessCache(grpcClients)))
+
 	api := app.Group("/api", gin.BasicAuth(allowedUsers))
+	// Limit after auth so unauthenticated requests don't use up the budget
+	api.Use(utils.RateLimitMiddlewareWithLimit(apiRateLimitPerMinute))
 	api.Use(grpcMiddleware(grpcClients))
 	api.GET("/summary", HandleSummary)
 	api.GET("/recommendation", HandleRecommendation)
+	api.GET("/models", HandleModels)
+	api.POST("/reprocess/:id", HandleReprocess)
 
 	v1 := api.Group("/v1")
 	v1.Use(utils.RateLimitMiddleware())
@@ -178,9 +384,69 @@ func run(cfg Config) error {
 	if err := validateAllowedUsersFormat(cfg.AllowedUsers); err != nil {
 		return err
 	}
+	if err := utils.ValidateRecommendTopTasksPrompt(utils.DefaultPromptToRecommendTopTasks); err != nil {
+		return fmt.Errorf("invalid recommendation prompt: %w", err)
+	}
 	if cfg.DependencyAddr == "" {
 		cfg.DependencyAddr = cfg.TodoAddr
 	}
+	if cfg.SummaryCron != "" {
+		if _, _, err := parseTimeOfDay(cfg.SummaryCron); err != nil {
+			return fmt.Errorf("invalid summary-cron: %w", err)
+		}
+		if cfg.SummaryWebhook == "" {
+			return errors.New("summary-cron needs summary-webhook: the scheduled summary would be generated for no one")
+		}
+	}
+	categories, err := utils.ParseSummaryCategories(cfg.SummaryCategories)
+	if err != nil {
+		return fmt.Errorf("invalid summary-categories: %w", err)
+	}
+	headerTemplate, err := parseEntryHeaderTemplate(cfg.SummaryEntryHeader)
+	if err != nil {
+		return fmt.Errorf("invalid summary-entry-header: %w", err)
+	}
+	if cfg.GinMode != "" {
+		if !slices.Contains([]string{gin.ReleaseMode, gin.DebugMode, gin.TestMode}, cfg.GinMode) {
+			return fmt.Errorf("invalid gin-mode %q: must be release, debug or test", cfg.GinMode)
+		}
+		ginMode = cfg.GinMode
+	}
+	healthTimeouts, err := parseKnownServiceTimeouts(cfg, cfg.HealthCheckTimeouts)
+	if err != nil {
+		return fmt.Errorf("invalid health-check-timeouts: %w", err)
+	}
+	if cfg.RecommendationMaxEntries < 0 {
+		return fmt.Errorf("invalid recommendation-max-entries %d: must not be negative", cfg.RecommendationMaxEntries)
+	}
+	if cfg.EntryLimitMaxAgeHours <= 0 {
+		return fmt.Errorf("invalid entry-limit-max-age-hours %d: must be positive", cfg.EntryLimitMaxAgeHours)
+	}
+	dbType := pb.DatabaseType_DATABASE_TYPE_SQLITE
+	if cfg.DatabaseType != "" {
+		if dbType, err = parseDatabaseType(cfg.DatabaseType); err != nil {
+			return fmt.Errorf("invalid database-type: %w", err)
+		}
+	}
+	if cfg.ConnectTimeout < 0 {
+		return fmt.Errorf("invalid connect-timeout %d: must not be negative", cfg.ConnectTimeout)
+	}
+	if _, err := parseKnownServiceTimeouts(cfg, cfg.ConnectTimeouts); err != nil {
+		return fmt.Errorf("invalid connect-timeouts: %w", err)
+	}
+
+	for _, timeout := range []struct {
+		flag    string
+		seconds int
+	}{
+		{"http-read-timeout", cfg.HTTPReadTimeout},
+		{"http-write-timeout", cfg.HTTPWriteTimeout},
+		{"http-idle-timeout", cfg.HTTPIdleTimeout},
+	} {
+		if timeout.seconds < 0 {
+			return fmt.Errorf("invalid %s %d: must not be negative", timeout.flag, timeout.seconds)
+		}
+	}
 
 	grpcClients, err := createClients(cfg)
 	if err != nil {
@@ -188,7 +454,7 @@ func run(cfg Config) error {
 	}
 	defer grpcClients.Close()
 
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.HealthCheckTimeout)*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), healthCheckDeadline(cfg, healthTimeouts))
 	defer cancel()
 
 	if err := grpcClients.WaitForHealthy(ctx); err != nil {
@@ -199,7 +465,13 @@ func run(cfg Config) error {
 	if cfg.DataBasePath == "" {
 		return errors.New("no database path provided. use --database-path flag to specify it")
 	}
-	if err := grpcClients.SetUpDataBase(cfg.DataBasePath); err != nil {
+	databaseType = dbType
+	setup := databaseSetup{
+		maxAttempts:  cfg.DatabaseSetupAttempts,
+		retryBackoff: defaultDatabaseSetupRetryBackoff,
+		timeout:      defaultDatabaseSetupTimeout,
+	}
+	if err := grpcClients.SetUpDataBase(cfg.DataBasePath, setup); err != nil {
 		return fmt.Errorf("failed to set up database: %w", err)
 	}
 	log.Infof("Database successfully set up at %s", cfg.DataBasePath)
@@ -210,6 +482,40 @@ func run(cfg Config) error {
 	}
 	log.Infof("Allowed users (hidden passwords): %s", allowedUsersStrings)
 
+	systemEmailSender = cfg.SystemEmailSender
+	allowedSenderDomains = parseSenderDomains(cfg.AllowedSenderDomains)
+	prioritySenders = parsePrioritySenders(cfg.PrioritySenders)
+	recommendationWebhookURL = cfg.RecommendationWebhook
+	summaryWebhookURL = cfg.SummaryWebhook
+	recommendationMaxEntries = cfg.RecommendationMaxEntries
+	entryLimitMaxAge = time.Duration(cfg.EntryLimitMaxAgeHours) * time.Hour
+	if cfg.SummaryEmptyMessage != "" {
+		summaryEmptyMessage = cfg.SummaryEmptyMessage
+	}
+	summarySkipEmpty = cfg.SummarySkipEmpty
+	if cfg.SummarySplitter != "" {
+		entrySplitter = cfg.SummarySplitter
+	}
+	summaryCategories = categories
+	entryHeaderTemplate = headerTemplate
+	entriesAsJSON = cfg.LLMEntriesJSON
+	promptPrefix, promptSuffix = cfg.PromptPrefix, cfg.PromptSuffix
+	emailParseOptions = utils.ParseOptions{DropImages: cfg.StripEmailImages, FlattenTables: cfg.FlattenEmailTables}
+	annotateTaskModel = cfg.AnnotateTaskModel
+	deadLetterDir = cfg.DeadLetterDir
+	apiRateLimitPerMinute = cfg.APIRateLimit
+	serverTimeouts = httpServerTimeouts{
+		Read:  time.Duration(cfg.HTTPReadTimeout) * time.Second,
+		Write: time.Duration(cfg.HTTPWriteTimeout) * time.Second,
+		Idle:  time.Duration(cfg.HTTPIdleTimeout) * time.Second,
+	}
+
+	if cfg.SummaryCron != "" {
+		if err := startSummaryScheduler(cfg.SummaryCron, grpcClients); err != nil {
+			return err
+		}
+	}
+
 	app, err := createRouter(allowedUserMap, grpcClients)
 	if err != nil {
 		return fmt.Errorf("failed to create router: %w", err)
@@ -225,6 +531,24 @@ func run(cfg Config) error {
 	return nil
 }
 
+// startSummaryScheduler runs the daily summary in the background at timeOfDay.
+func startSummaryScheduler(timeOfDay string, clients startupClients) error {
+	provider, ok := clients.(ClientProvider)
+	if !ok {
+		return fmt.Errorf("summary scheduler needs gRPC clients, got %T", clients)
+	}
+	scheduler, err := newSummaryScheduler(timeOfDay, func(ctx context.Context) {
+		runScheduledSummary(ctx, provider)
+	})
+	if err != nil {
+		return fmt.Errorf("invalid summary-cron: %w", err)
+	}
+
+	log.Infof("Daily summary scheduled at %s", timeOfDay)
+	go scheduler.Start(context.Background())
+	return nil
+}
+
 func main() {
 	os.Exit(executeMain())
 }
@@ -232,9 +556,14 @@ func main() {
 func executeMain() int {
 	initLogger()
 	initFlags()
-	log.Infof("Server Starting time: %s", time.Now().Format(time.RFC3339))
 	flag.Parse()
 
+	if config.ShowVersion {
+		utils.PrintVersion(versionOutput, "todofy", GitCommit, BuildDate)
+		return 0
+	}
+	log.Infof("Server Starting time: %s", time.Now().Format(time.RFC3339))
+
 	if err := runApplication(config); err != nil {
 		log.Errorf("Application startup failed: %v", err)
 		return 1
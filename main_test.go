@@ -1,6 +1,7 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
@@ -9,6 +10,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
@@ -25,6 +27,7 @@ type fakeStartupClients struct {
 	serviceList []string
 	closed      bool
 	setupPath   string
+	setup       databaseSetup
 }
 
 func (f *fakeStartupClients) Close() {
@@ -35,8 +38,9 @@ func (f *fakeStartupClients) WaitForHealthy(_ context.Context) error {
 	return f.waitErr
 }
 
-func (f *fakeStartupClients) SetUpDataBase(path string) error {
+func (f *fakeStartupClients) SetUpDataBase(path string, setup databaseSetup) error {
 	f.setupPath = path
+	f.setup = setup
 	return f.setDBErr
 }
 
@@ -104,12 +108,96 @@ func TestInitFlagsWithFlagSet_Defaults(t *testing.T) {
 	require.NoError(t, fs.Parse([]string{}))
 	assert.Equal(t, "", cfg.AllowedUsers)
 	assert.Equal(t, "", cfg.DataBasePath)
+	assert.Equal(t, "sqlite", cfg.DatabaseType)
 	assert.Equal(t, 8080, cfg.Port)
 	assert.Equal(t, 10, cfg.HealthCheckTimeout)
+	assert.Equal(t, "", cfg.HealthCheckTimeouts)
+	assert.Equal(t, 0, cfg.ConnectTimeout)
+	assert.Equal(t, "", cfg.ConnectTimeouts)
+	assert.Equal(t, 500, cfg.HealthCheckInterval)
+	assert.False(t, cfg.HealthCheckFailFast)
+	assert.Equal(t, 3, cfg.DatabaseSetupAttempts)
+	assert.Equal(t, 30, cfg.HTTPReadTimeout)
+	assert.Equal(t, 300, cfg.HTTPWriteTimeout)
+	assert.Equal(t, 120, cfg.HTTPIdleTimeout)
 	assert.Equal(t, ":50051", cfg.LLMAddr)
 	assert.Equal(t, ":50052", cfg.TodoAddr)
 	assert.Equal(t, "", cfg.DependencyAddr)
 	assert.Equal(t, ":50053", cfg.DatabaseAddr)
+	assert.Equal(t, "", cfg.GRPCAuthToken)
+	assert.Equal(t, "", cfg.SystemEmailSender)
+	assert.Equal(t, "", cfg.AllowedSenderDomains)
+	assert.Equal(t, "", cfg.PrioritySenders)
+	assert.Equal(t, "", cfg.RecommendationWebhook)
+	assert.Equal(t, 0, cfg.RecommendationMaxEntries)
+	assert.Equal(t, defaultEntryLimitMaxAgeHours, cfg.EntryLimitMaxAgeHours)
+	assert.Equal(t, "", cfg.SummaryCron)
+	assert.Equal(t, "", cfg.SummaryWebhook)
+	assert.Equal(t, defaultSummaryEmptyMessage, cfg.SummaryEmptyMessage)
+	assert.False(t, cfg.SummarySkipEmpty)
+	assert.Equal(t, defaultEntrySplitter, cfg.SummarySplitter)
+	assert.Equal(t, "", cfg.SummaryCategories)
+	assert.Equal(t, "", cfg.SummaryEntryHeader)
+	assert.False(t, cfg.LLMEntriesJSON)
+	assert.Equal(t, "", cfg.PromptPrefix)
+	assert.Equal(t, "", cfg.PromptSuffix)
+	assert.False(t, cfg.StripEmailImages)
+	assert.False(t, cfg.FlattenEmailTables)
+	assert.False(t, cfg.AnnotateTaskModel)
+	assert.Equal(t, "", cfg.DeadLetterDir)
+	assert.Equal(t, gin.ReleaseMode, cfg.GinMode)
+	assert.Equal(t, 0, cfg.APIRateLimit)
+}
+
+func TestBuildServiceConfigs_HealthSettings(t *testing.T) {
+	serviceConfigs := buildServiceConfigs(Config{
+		HealthCheckTimeout:  10,
+		HealthCheckTimeouts: "database=30",
+		HealthCheckInterval: 200,
+	})
+	for _, serviceConfig := range serviceConfigs {
+		expected := 10 * time.Second
+		if serviceConfig.name == "database" {
+			expected = 30 * time.Second
+		}
+		assert.Equal(t, expected, serviceConfig.healthTimeout, serviceConfig.name)
+		assert.Equal(t, 200*time.Millisecond, serviceConfig.healthInterval, serviceConfig.name)
+	}
+}
+
+func TestBuildServiceConfigs_ConnectTimeouts(t *testing.T) {
+	for _, serviceConfig := range buildServiceConfigs(Config{ConnectTimeout: 5, ConnectTimeouts: "llm=20"}) {
+		expected := 5 * time.Second
+		if serviceConfig.name == "llm" {
+			expected = 20 * time.Second
+		}
+		assert.Equal(t, expected, serviceConfig.connectTimeout, serviceConfig.name)
+	}
+	for _, serviceConfig := range buildServiceConfigs(Config{}) {
+		assert.Zero(t, serviceConfig.connectTimeout, "connections stay lazy by default")
+	}
+}
+
+func TestParseServiceTimeouts(t *testing.T) {
+	timeouts, err := parseServiceTimeouts(" database=30, llm = 5 ,")
+	require.NoError(t, err)
+	assert.Equal(t, map[string]time.Duration{"database": 30 * time.Second, "llm": 5 * time.Second}, timeouts)
+
+	timeouts, err = parseServiceTimeouts("")
+	require.NoError(t, err)
+	assert.Empty(t, timeouts)
+
+	for _, invalid := range []string{"database", "=30", "database=abc", "database=0", "database=-1"} {
+		_, err := parseServiceTimeouts(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestHealthCheckDeadline(t *testing.T) {
+	cfg := Config{HealthCheckTimeout: 10}
+	assert.Equal(t, 10*time.Second, healthCheckDeadline(cfg, nil))
+	assert.Equal(t, 10*time.Second, healthCheckDeadline(cfg, map[string]time.Duration{"llm": 5 * time.Second}))
+	assert.Equal(t, 30*time.Second, healthCheckDeadline(cfg, map[string]time.Duration{"database": 30 * time.Second}))
 }
 
 func TestBuildServiceConfigs(t *testing.T) {
@@ -118,9 +206,13 @@ func TestBuildServiceConfigs(t *testing.T) {
 		TodoAddr:       "todo:50052",
 		DependencyAddr: "dependency:50054",
 		DatabaseAddr:   "database:50053",
+		GRPCAuthToken:  "shared-secret",
 	}
 	serviceConfigs := buildServiceConfigs(cfg)
 	require.Len(t, serviceConfigs, 4)
+	for _, serviceConfig := range serviceConfigs {
+		assert.Equal(t, "shared-secret", serviceConfig.authToken)
+	}
 	assert.Equal(t, "llm", serviceConfigs[0].name)
 	assert.Equal(t, "llm:50051", serviceConfigs[0].addr)
 	assert.Equal(t, "todo", serviceConfigs[1].name)
@@ -193,10 +285,11 @@ func TestRun(t *testing.T) {
 	})
 
 	baseCfg := Config{
-		AllowedUsers:       "user:pass",
-		DataBasePath:       "/tmp/test.db",
-		Port:               12345,
-		HealthCheckTimeout: 1,
+		AllowedUsers:          "user:pass",
+		DataBasePath:          "/tmp/test.db",
+		Port:                  12345,
+		HealthCheckTimeout:    1,
+		EntryLimitMaxAgeHours: defaultEntryLimitMaxAgeHours,
 	}
 
 	t.Run("defaults dependency addr to todo addr when omitted", func(t *testing.T) {
@@ -226,6 +319,136 @@ func TestRun(t *testing.T) {
 		assert.Contains(t, err.Error(), "no allowed users provided")
 	})
 
+	t.Run("errors on invalid summary cron before creating clients", func(t *testing.T) {
+		createClients = func(Config) (startupClients, error) {
+			t.Fatal("clients should not be created with an invalid summary-cron")
+			return nil, nil
+		}
+		cfg := baseCfg
+		cfg.SummaryCron = "25:99"
+		err := run(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid summary-cron")
+	})
+
+	t.Run("errors on summary cron without a webhook before creating clients", func(t *testing.T) {
+		createClients = func(Config) (startupClients, error) {
+			t.Fatal("clients should not be created when the scheduled summary has nowhere to go")
+			return nil, nil
+		}
+		cfg := baseCfg
+		cfg.SummaryCron = "07:30"
+		err := run(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "summary-cron needs summary-webhook")
+	})
+
+	t.Run("errors on invalid summary categories before creating clients", func(t *testing.T) {
+		createClients = func(Config) (startupClients, error) {
+			t.Fatal("clients should not be created with invalid summary-categories")
+			return nil, nil
+		}
+		cfg := baseCfg
+		cfg.SummaryCategories = "Urgent,urgent"
+		err := run(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid summary-categories")
+	})
+
+	t.Run("errors on invalid summary entry header before creating clients", func(t *testing.T) {
+		createClients = func(Config) (startupClients, error) {
+			t.Fatal("clients should not be created with an invalid summary-entry-header")
+			return nil, nil
+		}
+		cfg := baseCfg
+		cfg.SummaryEntryHeader = "{{.Sender}}"
+		err := run(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid summary-entry-header")
+	})
+
+	t.Run("errors on invalid gin mode before creating clients", func(t *testing.T) {
+		createClients = func(Config) (startupClients, error) {
+			t.Fatal("clients should not be created with an invalid gin-mode")
+			return nil, nil
+		}
+		cfg := baseCfg
+		cfg.GinMode = "verbose"
+		err := run(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid gin-mode")
+	})
+
+	t.Run("errors on invalid health check timeouts before creating clients", func(t *testing.T) {
+		createClients = func(Config) (startupClients, error) {
+			t.Fatal("clients should not be created with invalid health-check-timeouts")
+			return nil, nil
+		}
+		for _, value := range []string{"database=abc", "databse=30"} {
+			cfg := baseCfg
+			cfg.HealthCheckTimeouts = value
+			err := run(cfg)
+			require.Error(t, err, value)
+			assert.Contains(t, err.Error(), "invalid health-check-timeouts")
+		}
+	})
+
+	t.Run("errors on invalid connect timeouts before creating clients", func(t *testing.T) {
+		createClients = func(Config) (startupClients, error) {
+			t.Fatal("clients should not be created with invalid connect timeouts")
+			return nil, nil
+		}
+		cfg := baseCfg
+		cfg.ConnectTimeout = -1
+		err := run(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid connect-timeout")
+
+		for _, value := range []string{"llm=0", "lm=5"} {
+			cfg := baseCfg
+			cfg.ConnectTimeouts = value
+			err := run(cfg)
+			require.Error(t, err, value)
+			assert.Contains(t, err.Error(), "invalid connect-timeouts")
+		}
+	})
+
+	t.Run("errors on negative http timeouts before creating clients", func(t *testing.T) {
+		createClients = func(Config) (startupClients, error) {
+			t.Fatal("clients should not be created with invalid http timeouts")
+			return nil, nil
+		}
+		cfg := baseCfg
+		cfg.HTTPWriteTimeout = -1
+		err := run(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid http-write-timeout")
+	})
+
+	t.Run("errors on non-positive entry limit max age before creating clients", func(t *testing.T) {
+		createClients = func(Config) (startupClients, error) {
+			t.Fatal("clients should not be created with a non-positive entry-limit-max-age-hours")
+			return nil, nil
+		}
+		cfg := baseCfg
+		cfg.EntryLimitMaxAgeHours = 0
+		err := run(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid entry-limit-max-age-hours")
+	})
+
+	t.Run("errors on unknown database type before creating clients", func(t *testing.T) {
+		createClients = func(Config) (startupClients, error) {
+			t.Fatal("clients should not be created with an unknown database-type")
+			return nil, nil
+		}
+		cfg := baseCfg
+		cfg.DatabaseType = "mongodb"
+		err := run(cfg)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid database-type")
+	})
+
 	t.Run("propagates client creation errors", func(t *testing.T) {
 		createClients = func(Config) (startupClients, error) {
 			return nil, errors.New("create failed")
@@ -306,11 +529,14 @@ func TestRun(t *testing.T) {
 			return fakeRunner, nil
 		}
 
-		err := run(baseCfg)
+		cfg := baseCfg
+		cfg.DatabaseSetupAttempts = 5
+		err := run(cfg)
 		require.Error(t, err)
 		assert.Contains(t, err.Error(), "failed to start server")
 		assert.Equal(t, []string{":12345"}, fakeRunner.addrs)
 		assert.Equal(t, "/tmp/test.db", fakeClients.setupPath)
+		assert.Equal(t, 5, fakeClients.setup.maxAttempts)
 	})
 
 	t.Run("returns nil on successful startup", func(t *testing.T) {
@@ -386,6 +612,37 @@ func TestExecuteMain_ReturnsNonZeroOnStartupFailure(t *testing.T) {
 	assert.Equal(t, 1, executeMain())
 }
 
+func TestExecuteMain_VersionFlagPrintsAndSkipsStartup(t *testing.T) {
+	originalRunApplication := runApplication
+	originalCommandLine := flag.CommandLine
+	originalArgs := os.Args
+	originalConfig := config
+	originalVersionOutput := versionOutput
+	originalGitCommit := GitCommit
+	t.Cleanup(func() {
+		runApplication = originalRunApplication
+		flag.CommandLine = originalCommandLine
+		os.Args = originalArgs
+		config = originalConfig
+		versionOutput = originalVersionOutput
+		GitCommit = originalGitCommit
+	})
+
+	flag.CommandLine = flag.NewFlagSet("todofy-version-test", flag.ContinueOnError)
+	os.Args = []string{"todofy", "-version"}
+	GitCommit = "abc123"
+	var out bytes.Buffer
+	versionOutput = &out
+
+	runApplication = func(Config) error {
+		t.Fatal("runApplication should not be called with -version")
+		return nil
+	}
+
+	assert.Equal(t, 0, executeMain())
+	assert.Contains(t, out.String(), "git commit: abc123")
+}
+
 func TestSetupRouter(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 
@@ -407,6 +664,15 @@ func TestSetupRouter(t *testing.T) {
 		assert.Equal(t, "todofy", body["service"])
 	})
 
+	t.Run("probe endpoints respond without auth", func(t *testing.T) {
+		for _, path := range []string{"/livez", "/readyz"} {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, path, nil)
+			router.ServeHTTP(w, req)
+			assert.Equal(t, http.StatusOK, w.Code, path)
+		}
+	})
+
 	t.Run("api routes require basic auth", func(t *testing.T) {
 		w := httptest.NewRecorder()
 		req, _ := http.NewRequest(http.MethodGet, "/api/summary", nil)
@@ -421,3 +687,67 @@ func TestSetupRouter(t *testing.T) {
 		assert.Equal(t, http.StatusUnauthorized, w.Code)
 	})
 }
+
+func TestSetupRouter_AppliesGinMode(t *testing.T) {
+	originalMode := ginMode
+	t.Cleanup(func() {
+		ginMode = originalMode
+		gin.SetMode(gin.TestMode)
+	})
+
+	cfg := Config{}
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	initFlagsWithFlagSet(fs, &cfg)
+	require.NoError(t, fs.Parse([]string{"-gin-mode=debug"}))
+	ginMode = cfg.GinMode
+
+	setupRouter(gin.Accounts{"testuser": "testpass"}, &GRPCClients{services: map[string]*serviceState{}})
+	assert.Equal(t, gin.DebugMode, gin.Mode())
+}
+
+func TestSetupRouter_UnknownRoutesReturnJSON(t *testing.T) {
+	t.Cleanup(func() { gin.SetMode(gin.TestMode) })
+	router := setupRouter(gin.Accounts{"testuser": "testpass"}, &GRPCClients{services: map[string]*serviceState{}})
+	serve := func(method, path string) (int, map[string]any) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.SetBasicAuth("testuser", "testpass")
+		router.ServeHTTP(w, req)
+		assert.Contains(t, w.Header().Get("Content-Type"), "application/json")
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w.Code, body
+	}
+
+	code, body := serve(http.MethodGet, "/api/unknown")
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, map[string]any{"error": "route not found", "path": "/api/unknown"}, body)
+
+	code, body = serve(http.MethodDelete, "/health")
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+	assert.Equal(t, map[string]any{"error": "method not allowed", "method": "DELETE"}, body)
+}
+
+func TestSetupRouter_APIRateLimitCoversSummary(t *testing.T) {
+	originalLimit := apiRateLimitPerMinute
+	t.Cleanup(func() {
+		apiRateLimitPerMinute = originalLimit
+		gin.SetMode(gin.TestMode)
+	})
+	apiRateLimitPerMinute = 1
+
+	router := setupRouter(gin.Accounts{"testuser": "testpass"}, &GRPCClients{services: map[string]*serviceState{}})
+	serve := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		req.SetBasicAuth("testuser", "testpass")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, serve("/api/models").Code)
+	// The /api budget is shared, so the summary call is throttled before reaching the LLM
+	throttled := serve("/api/summary")
+	assert.Equal(t, http.StatusTooManyRequests, throttled.Code)
+	assert.NotEmpty(t, throttled.Header().Get("Retry-After"))
+}
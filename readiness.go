@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// readinessCheckTimeout bounds one /readyz probe of the backends.
+	readinessCheckTimeout = 2 * time.Second
+	// readinessCacheTTL is how long a /readyz result is reused, so frequent polls don't probe every time.
+	readinessCacheTTL = 5 * time.Second
+)
+
+// healthChecker is the part of GRPCClients /readyz needs.
+type healthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+// readinessCache runs the backend health check behind /readyz and remembers its result for ttl.
+// Concurrent polls wait for the check in flight instead of starting their own.
+type readinessCache struct {
+	backends healthChecker
+	timeout  time.Duration
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	err       error
+}
+
+func newReadinessCache(backends healthChecker) *readinessCache {
+	return &readinessCache{
+		backends: backends,
+		timeout:  readinessCheckTimeout,
+		ttl:      readinessCacheTTL,
+		now:      time.Now,
+	}
+}
+
+// check returns the cached result while it is fresh, and re-probes the backends otherwise.
+func (r *readinessCache) check(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.checkedAt.IsZero() && r.now().Sub(r.checkedAt) < r.ttl {
+		return r.err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	r.err = r.backends.CheckHealth(ctx)
+	r.checkedAt = r.now()
+	return r.err
+}
+
+// handleLivez reports that the process is up; it never touches the backends.
+func handleLivez(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "alive"})
+}
+
+// handleReadyz answers 200 when every backend service is healthy and 503 otherwise.
+func handleReadyz(readiness *readinessCache) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := readiness.check(c.Request.Context()); err != nil {
+			log.Warningf("Readiness check failed: %v", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "not ready", "error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"status": "ready"})
+	}
+}
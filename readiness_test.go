@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type fakeHealthChecker struct {
+	err   error
+	calls int
+}
+
+func (f *fakeHealthChecker) CheckHealth(ctx context.Context) error {
+	f.calls++
+	if _, ok := ctx.Deadline(); !ok {
+		return errors.New("readiness check has no deadline")
+	}
+	return f.err
+}
+
+func serveProbe(t *testing.T, handler gin.HandlerFunc, path string) (int, map[string]any) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET(path, handler)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	router.ServeHTTP(w, req)
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	return w.Code, body
+}
+
+func TestHandleLivez(t *testing.T) {
+	code, body := serveProbe(t, handleLivez, "/livez")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "alive", body["status"])
+}
+
+func TestHandleReadyz(t *testing.T) {
+	t.Run("healthy backends", func(t *testing.T) {
+		backends := &fakeHealthChecker{}
+		code, body := serveProbe(t, handleReadyz(newReadinessCache(backends)), "/readyz")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "ready", body["status"])
+		assert.Equal(t, 1, backends.calls)
+	})
+
+	t.Run("unhealthy backend", func(t *testing.T) {
+		backends := &fakeHealthChecker{err: errors.New("health check failed: database")}
+		code, body := serveProbe(t, handleReadyz(newReadinessCache(backends)), "/readyz")
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "not ready", body["status"])
+		assert.Equal(t, "health check failed: database", body["error"])
+	})
+}
+
+func TestReadinessCache_LargeHealthCheckInterval(t *testing.T) {
+	conn, cleanup := newBufconnConn(t, true)
+	defer cleanup()
+
+	// A probe interval longer than the readiness timeout must not fail /readyz
+	clients := &GRPCClients{
+		services: map[string]*serviceState{
+			"database": {conn: conn, healthInterval: 2 * readinessCheckTimeout},
+		},
+	}
+
+	start := time.Now()
+	require.NoError(t, newReadinessCache(clients).check(context.Background()))
+	assert.Less(t, time.Since(start), readinessCheckTimeout)
+}
+
+func TestHandleReadyz_BackendNotServing(t *testing.T) {
+	// The llm process is up, but Gemini is unreachable
+	clients := &GRPCClients{
+		services: map[string]*serviceState{
+			"llm": {conn: newHealthStatusConn(t, map[string]grpc_health_v1.HealthCheckResponse_ServingStatus{
+				"":       grpc_health_v1.HealthCheckResponse_SERVING,
+				"gemini": grpc_health_v1.HealthCheckResponse_NOT_SERVING,
+			})},
+		},
+	}
+
+	code, body := serveProbe(t, handleReadyz(newReadinessCache(clients)), "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Contains(t, body["error"], "gemini backend status NOT_SERVING")
+}
+
+func TestReadinessCache_ReusesResultWithinTTL(t *testing.T) {
+	now := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	backends := &fakeHealthChecker{err: errors.New("down")}
+	readiness := newReadinessCache(backends)
+	readiness.now = func() time.Time { return now }
+
+	require.Error(t, readiness.check(context.Background()))
+	backends.err = nil
+	now = now.Add(readinessCacheTTL - time.Second)
+	require.Error(t, readiness.check(context.Background()), "cached failure")
+	assert.Equal(t, 1, backends.calls)
+
+	now = now.Add(time.Second)
+	require.NoError(t, readiness.check(context.Background()))
+	assert.Equal(t, 2, backends.calls)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+)
+
+// serverTimingHeader carries per-step durations back to the caller (https://www.w3.org/TR/server-timing/).
+const serverTimingHeader = "Server-Timing"
+
+type stepTiming struct {
+	name     string
+	duration time.Duration
+}
+
+// stepTimings records how long each backend call of one request took. Every step refreshes the
+// Server-Timing header, so whichever response the handler ends with carries the steps run so far.
+type stepTimings struct {
+	header http.Header
+	steps  []stepTiming
+}
+
+func newStepTimings(c *gin.Context) *stepTimings {
+	return &stepTimings{header: c.Writer.Header()}
+}
+
+// record stores the time elapsed since start under name.
+func (t *stepTimings) record(name string, start time.Time) {
+	t.steps = append(t.steps, stepTiming{name: name, duration: time.Since(start)})
+	t.header.Set(serverTimingHeader, t.serverTiming())
+}
+
+// serverTiming formats the steps as a Server-Timing value, e.g. "summarize;dur=812.4, db_write;dur=3.1".
+func (t *stepTimings) serverTiming() string {
+	parts := make([]string, len(t.steps))
+	for i, step := range t.steps {
+		parts[i] = step.name + ";dur=" + formatMillis(step.duration)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// logFields returns one "<step>_ms" field per recorded step.
+func (t *stepTimings) logFields() logrus.Fields {
+	fields := make(logrus.Fields, len(t.steps))
+	for _, step := range t.steps {
+		fields[step.name+"_ms"] = formatMillis(step.duration)
+	}
+	return fields
+}
+
+func formatMillis(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 1, 64)
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStepTimings(t *testing.T) {
+	timings := &stepTimings{header: http.Header{}}
+	assert.Empty(t, timings.serverTiming())
+
+	timings.record("summarize", time.Now().Add(-time.Second))
+	assert.Regexp(t, `^summarize;dur=1\d{3}\.\d$`, timings.header.Get(serverTimingHeader))
+
+	timings.steps = []stepTiming{
+		{name: "check_cache", duration: 1500 * time.Microsecond},
+		{name: "db_write", duration: 20 * time.Millisecond},
+	}
+	assert.Equal(t, "check_cache;dur=1.5, db_write;dur=20.0", timings.serverTiming())
+	assert.Equal(t, logrus.Fields{"check_cache_ms": "1.5", "db_write_ms": "20.0"}, timings.logFields())
+}
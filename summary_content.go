@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ziyixi/todofy/utils"
+
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/ziyixi/protos/go/todofy"
+)
+
+const defaultEntrySplitter = "========================="
+
+// entrySplitter separates entries in LLM input built from stored summaries; set from --summary-splitter.
+var entrySplitter = defaultEntrySplitter
+
+// entryHeaderTemplate renders each entry's header line in LLM input; set from --summary-entry-header.
+// Nil keeps the built-in "[date] subject:" header.
+var entryHeaderTemplate *template.Template
+
+// entryHeaderFields are the values available to an --summary-entry-header template.
+type entryHeaderFields struct {
+	Index   int // 1-based position of the entry in the content
+	Date    string
+	Subject string
+}
+
+// entriesAsJSON sends summary and recommendation entries to the LLM as a JSON array instead of
+// splitter-separated text; set from --llm-entries-json.
+var entriesAsJSON bool
+
+// jsonEntry is one stored entry in the --llm-entries-json LLM input.
+type jsonEntry struct {
+	Index          int    `json:"index"`
+	Date           string `json:"date,omitempty"`
+	Subject        string `json:"subject,omitempty"`
+	Sender         string `json:"sender,omitempty"`
+	PrioritySender bool   `json:"priority_sender,omitempty"`
+	Summary        string `json:"summary"`
+}
+
+// MaxEntryLimit caps ?limit=N on the summary and recommendation endpoints.
+const MaxEntryLimit = 200
+
+// defaultEntryLimitMaxAgeHours is how far back ?limit=N looks by default: 30 days.
+const defaultEntryLimitMaxAgeHours = 30 * 24
+
+// entryLimitMaxAge bounds how far back ?limit=N looks for the most recent entries; set from
+// --entry-limit-max-age-hours.
+var entryLimitMaxAge = time.Duration(defaultEntryLimitMaxAgeHours) * time.Hour
+
+// entryHeaderDateLayout formats an entry's creation time in its header line.
+const entryHeaderDateLayout = "2006-01-02 15:04"
+
+// storedSubjectPattern and storedSenderPattern match the subject and sender lines rendered by
+// templates/todoDescription.tmpl.
+var (
+	storedSubjectPattern = regexp.MustCompile(`(?m)^\*\*SUBJECT: (.*)\*\*$`)
+	storedSenderPattern  = regexp.MustCompile(`(?m)^\*\*FROM: (.*)\*\*$`)
+)
+
+// prioritySenders lists lowercased sender addresses whose tasks the recommendation prompt
+// marks with utils.PrioritySenderMarker; set from --priority-senders.
+var prioritySenders []string
+
+// queryEntries returns the stored entries created within window or, when limit > 0, the
+// limit most recent entries created within entryLimitMaxAge. QueryRecent only filters by
+// time, so the count-based mode queries that whole window and keeps the newest.
+func queryEntries(
+	ctx context.Context, databaseClient pb.DataBaseServiceClient, window time.Duration, limit int,
+) ([]*pb.DataBaseSchema, error) {
+	timeAgo := int64(window.Seconds())
+	if limit > 0 {
+		timeAgo = int64(entryLimitMaxAge.Seconds())
+	}
+	resp, err := databaseClient.QueryRecent(ctx, &pb.QueryRecentRequest{
+		Type:             databaseType,
+		TimeAgoInSeconds: timeAgo,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return newestEntries(resp.Entries, limit), nil
+}
+
+// newestEntries returns the n most recently created entries, oldest first, or all of them
+// when n <= 0 or there are no more than n.
+func newestEntries(entries []*pb.DataBaseSchema, n int) []*pb.DataBaseSchema {
+	if n <= 0 || len(entries) <= n {
+		return entries
+	}
+	// Oldest first, like the time-windowed query, so the kept tail is the newest
+	sorted := slices.Clone(entries)
+	slices.SortStableFunc(sorted, func(a, b *pb.DataBaseSchema) int {
+		return a.CreatedAt.AsTime().Compare(b.CreatedAt.AsTime())
+	})
+	return sorted[len(sorted)-n:]
+}
+
+// parseEntryLimit reads the optional ?limit=N entry count (0 when absent).
+func parseEntryLimit(c *gin.Context) (int, error) {
+	raw := c.Query("limit")
+	if raw == "" {
+		return 0, nil
+	}
+	limit, err := strconv.Atoi(raw)
+	if err != nil || limit < 1 || limit > MaxEntryLimit {
+		return 0, fmt.Errorf("invalid limit parameter: must be 1-%d", MaxEntryLimit)
+	}
+	return limit, nil
+}
+
+// buildEntriesContent joins stored entries into LLM input, each prefixed by a
+// compact header (by default "[date] subject:") so the model can weigh recency and context.
+// It backs both the summary and the recommendation prompts; markPriority prefixes the
+// header of entries from prioritySenders with utils.PrioritySenderMarker.
+func buildEntriesContent(entries []*pb.DataBaseSchema, markPriority bool) string {
+	if entriesAsJSON {
+		return buildEntriesJSON(entries, markPriority)
+	}
+	splitter := entrySplitter + "\n"
+	var content strings.Builder
+	content.WriteString(splitter)
+	for i, entry := range entries {
+		header := formatEntryHeader(i+1, entry)
+		if markPriority && isPrioritySender(entry) {
+			header = strings.TrimSpace(utils.PrioritySenderMarker + " " + header)
+		}
+		if header != "" {
+			content.WriteString(header + "\n")
+		}
+		content.WriteString(entry.Summary + "\n" + splitter)
+	}
+	return content.String()
+}
+
+// buildEntriesJSON renders entries as the --llm-entries-json array; markPriority sets
+// priority_sender on entries from prioritySenders.
+func buildEntriesJSON(entries []*pb.DataBaseSchema, markPriority bool) string {
+	items := make([]jsonEntry, len(entries))
+	for i, entry := range entries {
+		items[i] = jsonEntry{
+			Index:          i + 1,
+			Subject:        storedSubject(entry.Summary),
+			Sender:         storedSender(entry.Summary),
+			PrioritySender: markPriority && isPrioritySender(entry),
+			Summary:        entry.Summary,
+		}
+		if entry.CreatedAt != nil {
+			items[i].Date = entry.CreatedAt.AsTime().Local().Format(entryHeaderDateLayout)
+		}
+	}
+	// Only strings, ints and bools are marshaled, which cannot fail
+	content, _ := json.MarshalIndent(items, "", "  ")
+	return string(content)
+}
+
+// entriesPrompt adds the description of the JSON input format to prompt under --llm-entries-json.
+func entriesPrompt(prompt string) string {
+	if !entriesAsJSON {
+		return prompt
+	}
+	return prompt + "\n" + utils.PromptEntriesJSON
+}
+
+// parseEntryHeaderTemplate parses an --summary-entry-header template, returning nil for "".
+// The template is test-executed so references to unknown fields fail at startup.
+func parseEntryHeaderTemplate(text string) (*template.Template, error) {
+	if text == "" {
+		return nil, nil
+	}
+	tmpl, err := template.New("entry-header").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmpl.Execute(io.Discard, entryHeaderFields{}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// formatEntryHeader renders the header of the index-th entry with entryHeaderTemplate,
+// falling back to entryHeader when no template is set or it fails.
+func formatEntryHeader(index int, entry *pb.DataBaseSchema) string {
+	if entryHeaderTemplate == nil {
+		return entryHeader(entry)
+	}
+	fields := entryHeaderFields{Index: index, Subject: storedSubject(entry.Summary)}
+	if entry.CreatedAt != nil {
+		fields.Date = entry.CreatedAt.AsTime().Local().Format(entryHeaderDateLayout)
+	}
+	var header strings.Builder
+	if err := entryHeaderTemplate.Execute(&header, fields); err != nil {
+		return entryHeader(entry)
+	}
+	return strings.TrimSpace(header.String())
+}
+
+// entryHeader returns the "[date] subject:" line for entry, omitting missing parts.
+func entryHeader(entry *pb.DataBaseSchema) string {
+	var parts []string
+	if entry.CreatedAt != nil {
+		parts = append(parts, "["+entry.CreatedAt.AsTime().Local().Format(entryHeaderDateLayout)+"]")
+	}
+	if subject := storedSubject(entry.Summary); subject != "" {
+		parts = append(parts, subject)
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return strings.Join(parts, " ") + ":"
+}
+
+// formatEntryTimestamp renders a stored entry time as UTC RFC3339, or "" when unset.
+func formatEntryTimestamp(ts *timestamppb.Timestamp) string {
+	if ts == nil {
+		return ""
+	}
+	return ts.AsTime().UTC().Format(time.RFC3339)
+}
+
+// parsePrioritySenders parses the comma-separated --priority-senders addresses.
+func parsePrioritySenders(raw string) []string {
+	var senders []string
+	for _, part := range strings.Split(raw, ",") {
+		if sender := strings.ToLower(emailAddress(part)); sender != "" {
+			senders = append(senders, sender)
+		}
+	}
+	return senders
+}
+
+// isPrioritySender reports whether entry's stored sender is one of prioritySenders.
+func isPrioritySender(entry *pb.DataBaseSchema) bool {
+	if len(prioritySenders) == 0 {
+		return false
+	}
+	sender := storedSender(entry.Summary)
+	if sender == "" {
+		return false
+	}
+	return slices.Contains(prioritySenders, strings.ToLower(emailAddress(sender)))
+}
+
+// storedSender extracts the sender line from a stored task body, or "" if absent.
+func storedSender(summary string) string {
+	match := storedSenderPattern.FindStringSubmatch(summary)
+	if match == nil {
+		return ""
+	}
+	return strings.TrimSpace(html.UnescapeString(match[1]))
+}
+
+// storedSubject extracts the email subject from a stored task body, or "" if absent.
+func storedSubject(summary string) string {
+	match := storedSubjectPattern.FindStringSubmatch(summary)
+	if match == nil {
+		return ""
+	}
+	// The stored body was rendered by html/template, so undo its escaping
+	return strings.TrimSpace(html.UnescapeString(match[1]))
+}
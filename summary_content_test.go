@@ -0,0 +1,208 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ziyixi/todofy/testutils/mocks"
+	"github.com/ziyixi/todofy/utils"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	pb "github.com/ziyixi/protos/go/todofy"
+)
+
+// useEntrySplitter overrides entrySplitter for the duration of a test.
+func useEntrySplitter(t *testing.T, splitter string) {
+	t.Helper()
+	original := entrySplitter
+	entrySplitter = splitter
+	t.Cleanup(func() {
+		entrySplitter = original
+	})
+}
+
+func TestEntryHeader(t *testing.T) {
+	created := time.Date(2024, 3, 5, 9, 30, 0, 0, time.UTC)
+	date := "[" + created.Local().Format(entryHeaderDateLayout) + "]"
+	stored := "**FROM: a@example.com**\n**SUBJECT: Q1 plan &amp; budget**\n\n=====\nbody"
+
+	assert.Equal(t, date+" Q1 plan & budget:",
+		entryHeader(&pb.DataBaseSchema{Summary: stored, CreatedAt: timestamppb.New(created)}))
+	assert.Equal(t, date+":", entryHeader(&pb.DataBaseSchema{Summary: "plain", CreatedAt: timestamppb.New(created)}))
+	assert.Equal(t, "Q1 plan & budget:", entryHeader(&pb.DataBaseSchema{Summary: stored}))
+	assert.Equal(t, "", entryHeader(&pb.DataBaseSchema{Summary: "plain"}))
+}
+
+func TestBuildEntriesContent(t *testing.T) {
+	useEntrySplitter(t, "---")
+	created := time.Date(2024, 3, 5, 9, 30, 0, 0, time.UTC)
+	date := "[" + created.Local().Format(entryHeaderDateLayout) + "]"
+
+	content := buildEntriesContent([]*pb.DataBaseSchema{
+		{Summary: "**SUBJECT: Report**\nbody one", CreatedAt: timestamppb.New(created)},
+		{Summary: "body two"},
+	}, false)
+	assert.Equal(t, "---\n"+date+" Report:\n**SUBJECT: Report**\nbody one\n---\nbody two\n---\n", content)
+}
+
+func TestBuildEntriesContent_HeaderTemplate(t *testing.T) {
+	useEntrySplitter(t, "---")
+	tmpl, err := parseEntryHeaderTemplate("#{{.Index}} {{.Date}} {{.Subject}}")
+	require.NoError(t, err)
+	original := entryHeaderTemplate
+	entryHeaderTemplate = tmpl
+	t.Cleanup(func() { entryHeaderTemplate = original })
+
+	created := time.Date(2024, 3, 5, 9, 30, 0, 0, time.UTC)
+	date := created.Local().Format(entryHeaderDateLayout)
+	content := buildEntriesContent([]*pb.DataBaseSchema{
+		{Summary: "**SUBJECT: Report**\nbody one", CreatedAt: timestamppb.New(created)},
+		{Summary: "body two"},
+	}, false)
+	assert.Equal(t, "---\n#1 "+date+" Report\n**SUBJECT: Report**\nbody one\n---\n#2\nbody two\n---\n", content)
+}
+
+// usePrioritySenders overrides prioritySenders for the duration of a test.
+func usePrioritySenders(t *testing.T, raw string) {
+	t.Helper()
+	original := prioritySenders
+	prioritySenders = parsePrioritySenders(raw)
+	t.Cleanup(func() { prioritySenders = original })
+}
+
+func TestBuildEntriesContent_MarksPrioritySenders(t *testing.T) {
+	useEntrySplitter(t, "---")
+	usePrioritySenders(t, "Boss <BOSS@example.com>, cfo@example.com")
+
+	entries := []*pb.DataBaseSchema{
+		{Summary: "**FROM: Boss &lt;boss@example.com&gt;**\n**SUBJECT: Review**\nbody one"},
+		{Summary: "**FROM: news@example.com**\n**SUBJECT: Sale**\nbody two"},
+		{Summary: "**FROM: cfo@example.com**\nbody three"},
+	}
+	marker := utils.PrioritySenderMarker
+	assert.Equal(t, "---\n"+marker+" Review:\n"+entries[0].Summary+"\n---\n"+
+		"Sale:\n"+entries[1].Summary+"\n---\n"+
+		marker+"\n"+entries[2].Summary+"\n---\n",
+		buildEntriesContent(entries, true))
+	assert.NotContains(t, buildEntriesContent(entries, false), marker)
+}
+
+// useEntriesJSON enables --llm-entries-json for the duration of a test.
+func useEntriesJSON(t *testing.T) {
+	t.Helper()
+	original := entriesAsJSON
+	entriesAsJSON = true
+	t.Cleanup(func() { entriesAsJSON = original })
+}
+
+func TestBuildEntriesContent_JSON(t *testing.T) {
+	useEntriesJSON(t)
+	usePrioritySenders(t, "boss@example.com")
+
+	created := time.Date(2024, 3, 5, 9, 30, 0, 0, time.UTC)
+	entries := []*pb.DataBaseSchema{
+		{
+			Summary:   "**FROM: Boss &lt;boss@example.com&gt;**\n**SUBJECT: Review**\nbody one",
+			CreatedAt: timestamppb.New(created),
+		},
+		{Summary: "plain body"},
+	}
+
+	var got []jsonEntry
+	require.NoError(t, json.Unmarshal([]byte(buildEntriesContent(entries, true)), &got))
+	assert.Equal(t, []jsonEntry{
+		{
+			Index:          1,
+			Date:           created.Local().Format(entryHeaderDateLayout),
+			Subject:        "Review",
+			Sender:         "Boss <boss@example.com>",
+			PrioritySender: true,
+			Summary:        entries[0].Summary,
+		},
+		{Index: 2, Summary: "plain body"},
+	}, got)
+
+	content := buildEntriesContent(entries, false)
+	assert.NotContains(t, content, "priority_sender")
+	assert.NotContains(t, content, defaultEntrySplitter)
+}
+
+func TestParsePrioritySenders(t *testing.T) {
+	assert.Equal(t, []string{"boss@example.com", "cfo@example.com"},
+		parsePrioritySenders(" Boss <Boss@Example.com> ,, cfo@example.com"))
+	assert.Nil(t, parsePrioritySenders(""))
+}
+
+func TestParseEntryHeaderTemplate(t *testing.T) {
+	tmpl, err := parseEntryHeaderTemplate("")
+	require.NoError(t, err)
+	assert.Nil(t, tmpl)
+
+	_, err = parseEntryHeaderTemplate("{{.Index")
+	assert.Error(t, err, "syntax errors are rejected")
+
+	_, err = parseEntryHeaderTemplate("{{.Sender}}")
+	assert.Error(t, err, "unknown fields are rejected")
+}
+
+func TestQueryEntries(t *testing.T) {
+	base := time.Date(2024, 3, 5, 9, 0, 0, 0, time.UTC)
+	entry := func(subject string, offset time.Duration) *pb.DataBaseSchema {
+		return &pb.DataBaseSchema{Summary: subject, CreatedAt: timestamppb.New(base.Add(offset))}
+	}
+	stored := []*pb.DataBaseSchema{
+		entry("b", time.Hour), entry("a", 0), entry("d", 3*time.Hour), entry("c", 2*time.Hour),
+	}
+
+	t.Run("time window by default", func(t *testing.T) {
+		mockDB := new(mocks.MockDataBaseServiceClient)
+		mockDB.On("QueryRecent", mock.Anything, mock.MatchedBy(func(req *pb.QueryRecentRequest) bool {
+			return req.TimeAgoInSeconds == int64((24 * time.Hour).Seconds())
+		}), mock.Anything).Return(&pb.QueryRecentResponse{Entries: stored}, nil)
+
+		entries, err := queryEntries(context.Background(), mockDB, 24*time.Hour, 0)
+		require.NoError(t, err)
+		assert.Equal(t, stored, entries)
+	})
+
+	t.Run("limit keeps the newest entries oldest first", func(t *testing.T) {
+		mockDB := new(mocks.MockDataBaseServiceClient)
+		mockDB.On("QueryRecent", mock.Anything, mock.MatchedBy(func(req *pb.QueryRecentRequest) bool {
+			return req.TimeAgoInSeconds == int64((defaultEntryLimitMaxAgeHours * time.Hour).Seconds())
+		}), mock.Anything).Return(&pb.QueryRecentResponse{Entries: stored}, nil)
+
+		entries, err := queryEntries(context.Background(), mockDB, 24*time.Hour, 2)
+		require.NoError(t, err)
+		require.Len(t, entries, 2)
+		assert.Equal(t, "c", entries[0].Summary)
+		assert.Equal(t, "d", entries[1].Summary)
+		assert.Equal(t, "b", stored[0].Summary, "the response slice is not reordered")
+	})
+
+	t.Run("sends the configured database type", func(t *testing.T) {
+		useDatabaseType(t, testDatabaseType)
+		mockDB := new(mocks.MockDataBaseServiceClient)
+		mockDB.On("QueryRecent", mock.Anything, mock.MatchedBy(func(req *pb.QueryRecentRequest) bool {
+			return req.Type == testDatabaseType
+		}), mock.Anything).Return(&pb.QueryRecentResponse{Entries: stored}, nil)
+
+		_, err := queryEntries(context.Background(), mockDB, 24*time.Hour, 0)
+		require.NoError(t, err)
+		mockDB.AssertExpectations(t)
+	})
+
+	t.Run("limit above the entry count keeps everything", func(t *testing.T) {
+		mockDB := new(mocks.MockDataBaseServiceClient)
+		mockDB.On("QueryRecent", mock.Anything, mock.Anything, mock.Anything).
+			Return(&pb.QueryRecentResponse{Entries: stored}, nil)
+
+		entries, err := queryEntries(context.Background(), mockDB, 24*time.Hour, 10)
+		require.NoError(t, err)
+		assert.Len(t, entries, len(stored))
+	})
+}
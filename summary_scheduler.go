@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// summaryWebhookURL receives each scheduled summary; set from --summary-webhook.
+var summaryWebhookURL string
+
+// summaryScheduler runs a job once a day at a fixed local time of day.
+type summaryScheduler struct {
+	hour   int
+	minute int
+	run    func(context.Context)
+
+	// now and after are injectable so tests can drive the schedule.
+	now   func() time.Time
+	after func(time.Duration) <-chan time.Time
+}
+
+// parseTimeOfDay parses a 24-hour "HH:MM" value such as "07:30".
+func parseTimeOfDay(value string) (hour, minute int, err error) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid time of day %q: expected HH:MM (24-hour)", value)
+	}
+	return parsed.Hour(), parsed.Minute(), nil
+}
+
+func newSummaryScheduler(timeOfDay string, run func(context.Context)) (*summaryScheduler, error) {
+	hour, minute, err := parseTimeOfDay(timeOfDay)
+	if err != nil {
+		return nil, err
+	}
+	return &summaryScheduler{
+		hour:   hour,
+		minute: minute,
+		run:    run,
+		now:    time.Now,
+		after:  time.After,
+	}, nil
+}
+
+// nextRun returns the first scheduled time strictly after now.
+func (s *summaryScheduler) nextRun(now time.Time) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), s.hour, s.minute, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+// Start blocks, running the job at each scheduled time until ctx is done.
+func (s *summaryScheduler) Start(ctx context.Context) {
+	for {
+		now := s.now()
+		next := s.nextRun(now)
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.after(next.Sub(now)):
+			s.run(ctx)
+		}
+	}
+}
+
+// runScheduledSummary generates the daily summary through the gRPC clients, as
+// HandleSummary would, and POSTs it to the summary webhook in the same JSON shape.
+func runScheduledSummary(ctx context.Context, clients ClientProvider) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	summary, taskCount, err := generateSummary(ctx, clients, 0)
+	if err != nil {
+		log.Errorf("Scheduled summary failed: %v", err)
+		return
+	}
+	if taskCount == 0 && summarySkipEmpty {
+		log.Info("Scheduled summary skipped: no tasks in the summary window")
+		return
+	}
+	if err := postWebhook(ctx, summaryWebhookURL, summaryResponse(summary, taskCount, 0)); err != nil {
+		log.Errorf("Scheduled summary of %d tasks could not be delivered: %v", taskCount, err)
+		return
+	}
+	log.Infof("Scheduled summary of %d tasks delivered", taskCount)
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"github.com/ziyixi/todofy/testutils/mocks"
+	"github.com/ziyixi/todofy/utils"
+
+	pb "github.com/ziyixi/protos/go/todofy"
+)
+
+func TestParseTimeOfDay(t *testing.T) {
+	hour, minute, err := parseTimeOfDay("07:30")
+	require.NoError(t, err)
+	assert.Equal(t, 7, hour)
+	assert.Equal(t, 30, minute)
+
+	for _, invalid := range []string{"7", "25:00", "07:60", "0 7 * * *"} {
+		_, _, err := parseTimeOfDay(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestSummaryScheduler_NextRun(t *testing.T) {
+	scheduler, err := newSummaryScheduler("07:30", nil)
+	require.NoError(t, err)
+
+	before := time.Date(2026, 3, 1, 6, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 3, 1, 7, 30, 0, 0, time.UTC), scheduler.nextRun(before))
+
+	exactly := time.Date(2026, 3, 1, 7, 30, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 3, 2, 7, 30, 0, 0, time.UTC), scheduler.nextRun(exactly))
+
+	after := time.Date(2026, 3, 31, 23, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2026, 4, 1, 7, 30, 0, 0, time.UTC), scheduler.nextRun(after))
+}
+
+func TestSummaryScheduler_FiresAtConfiguredTick(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ran := make(chan struct{}, 1)
+	scheduler, err := newSummaryScheduler("07:30", func(context.Context) {
+		ran <- struct{}{}
+	})
+	require.NoError(t, err)
+
+	now := time.Date(2026, 3, 1, 7, 0, 0, 0, time.UTC)
+	waits := make(chan time.Duration, 2)
+	tick := make(chan time.Time)
+	scheduler.now = func() time.Time { return now }
+	scheduler.after = func(d time.Duration) <-chan time.Time {
+		waits <- d
+		return tick
+	}
+
+	done := make(chan struct{})
+	go func() {
+		scheduler.Start(ctx)
+		close(done)
+	}()
+
+	assert.Equal(t, 30*time.Minute, <-waits)
+	select {
+	case <-ran:
+		t.Fatal("summary ran before the scheduled tick")
+	default:
+	}
+
+	tick <- now.Add(30 * time.Minute)
+	select {
+	case <-ran:
+	case <-time.After(time.Second):
+		t.Fatal("summary did not run at the scheduled tick")
+	}
+
+	// The scheduler then waits for the next day's slot until cancelled
+	<-waits
+	cancel()
+	<-done
+}
+
+// useSummaryWebhook points --summary-webhook at a test server that records posted bodies and
+// answers with status, retrying quickly.
+func useSummaryWebhook(t *testing.T, status int) *[]map[string]any {
+	t.Helper()
+	var mu sync.Mutex
+	var posted []map[string]any
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		mu.Lock()
+		posted = append(posted, body)
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(webhook.Close)
+
+	originalURL, originalRetry := summaryWebhookURL, webhookRetry
+	summaryWebhookURL = webhook.URL
+	webhookRetry = utils.RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond}
+	t.Cleanup(func() { summaryWebhookURL, webhookRetry = originalURL, originalRetry })
+	return &posted
+}
+
+func scheduledSummaryClients(
+	entries []*pb.DataBaseSchema,
+) (*mocks.MockGRPCClients, *mocks.MockLLMSummaryServiceClient) {
+	mockDB := new(mocks.MockDataBaseServiceClient)
+	mockDB.On("QueryRecent", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.QueryRecentResponse{Entries: entries}, nil)
+	mockLLM := new(mocks.MockLLMSummaryServiceClient)
+	mockLLM.On("Summarize", mock.Anything, mock.Anything, mock.Anything).
+		Return(&pb.LLMSummaryResponse{Summary: "daily digest"}, nil)
+
+	clients := mocks.NewMockGRPCClients()
+	clients.SetClient("database", mockDB)
+	clients.SetClient("llm", mockLLM)
+	return clients, mockLLM
+}
+
+func TestRunScheduledSummary_PostsToWebhook(t *testing.T) {
+	posted := useSummaryWebhook(t, http.StatusOK)
+	clients, mockLLM := scheduledSummaryClients([]*pb.DataBaseSchema{{Summary: "s1"}})
+
+	runScheduledSummary(context.Background(), clients)
+
+	mockLLM.AssertExpectations(t)
+	assert.Equal(t, []map[string]any{
+		{"summary": "daily digest", "task_count": float64(1), "time_window_hours": float64(24)},
+	}, *posted)
+}
+
+func TestRunScheduledSummary_RetriesFailedDelivery(t *testing.T) {
+	posted := useSummaryWebhook(t, http.StatusBadGateway)
+	clients, _ := scheduledSummaryClients([]*pb.DataBaseSchema{{Summary: "s1"}})
+
+	runScheduledSummary(context.Background(), clients)
+
+	assert.Len(t, *posted, 3)
+}
+
+func TestRunScheduledSummary_SkipEmptyPostsNothing(t *testing.T) {
+	useSummaryEmptyBehavior(t, "", true)
+	posted := useSummaryWebhook(t, http.StatusOK)
+	clients, _ := scheduledSummaryClients(nil)
+
+	runScheduledSummary(context.Background(), clients)
+
+	assert.Empty(t, *posted)
+}
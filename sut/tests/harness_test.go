@@ -250,6 +250,16 @@ func sanitizeName(value string) string {
 	return sanitized
 }
 
+// summaryInput is the LLM input text the gateway builds for a cloudmailinPayload email.
+func summaryInput(subject string, plain string) string {
+	return utils.MailInputText(utils.MailInfo{
+		Subject: subject,
+		From:    "sender@example.com",
+		To:      "todo@example.com",
+		Content: plain,
+	})
+}
+
 func hashForSummary(text string) string {
 	return fmt.Sprintf("%x", sha256.Sum256([]byte(utils.DefaultPromptToSummaryEmail+text)))
 }
@@ -112,7 +112,8 @@ func TestSUTUpdateTodoIntegration(t *testing.T) {
 
 	t.Run("update todo cache hit skips gemini", func(t *testing.T) {
 		h.resetScenario(t)
-		emailText := "Please renew the registration."
+		plain := "Please renew the registration."
+		emailText := summaryInput("Registration", plain)
 		h.writeEntry(t, &pb.DataBaseSchema{
 			ModelFamily: pb.ModelFamily_MODEL_FAMILY_GEMINI,
 			Model:       pb.Model_MODEL_GEMINI_2_5_FLASH_LITE,
@@ -122,7 +123,7 @@ func TestSUTUpdateTodoIntegration(t *testing.T) {
 			HashId:      hashForSummary(emailText),
 		})
 
-		status, body := h.postAPI(t, "/api/v1/update_todo", cloudmailinPayload("Registration", emailText))
+		status, body := h.postAPI(t, "/api/v1/update_todo", cloudmailinPayload("Registration", plain))
 		require.Equal(t, http.StatusOK, status, string(body))
 
 		todoistState := h.todoistState(t)
@@ -11,6 +11,8 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/test/bufconn"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -77,6 +79,26 @@ func NewTestGRPCClient(t *testing.T, listener *bufconn.Listener) *grpc.ClientCon
 	return conn
 }
 
+// ServeBufconn starts a gRPC server on a bufconn listener with the services added by register
+// and a health service reporting SERVING. The server is stopped when the test ends.
+func ServeBufconn(t *testing.T, register func(grpc.ServiceRegistrar)) *bufconn.Listener {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer()
+	register(server)
+	healthServer := health.NewServer()
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(server, healthServer)
+
+	go func() {
+		_ = server.Serve(listener) // Returns once the server is stopped
+	}()
+	t.Cleanup(server.Stop)
+
+	return listener
+}
+
 // TempFile creates a temporary file for testing
 func TempFile(t *testing.T, prefix string) *os.File {
 	t.Helper()
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+
+	"github.com/ziyixi/todofy/todo/internal/todoist"
+)
+
+// todoistHealthService is the gRPC health service name reporting whether the
+// configured Todoist credentials are accepted. The overall ("") status is unaffected; the
+// gateway's /readyz checks this name too (backendHealthServices).
+const todoistHealthService = "todoist"
+
+const backendHealthCheckTimeout = 15 * time.Second
+
+// todoistCredentialChecker validates Todoist credentials with a lightweight API call.
+type todoistCredentialChecker interface {
+	CheckCredentials(ctx context.Context) error
+}
+
+// newTodoistCredentialChecker is injectable for tests.
+var newTodoistCredentialChecker = func(apiKey string) todoistCredentialChecker {
+	return todoist.NewClientWithBaseURL(apiKey, *todoistBaseURL)
+}
+
+// checkTodoistBackend reports SERVING only when an API key is configured and accepted.
+func checkTodoistBackend(ctx context.Context) healthpb.HealthCheckResponse_ServingStatus {
+	if *todoistAPIKey == "" {
+		log.Warn("Todoist backend health: no API key configured")
+		return healthpb.HealthCheckResponse_NOT_SERVING
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, backendHealthCheckTimeout)
+	defer cancel()
+	if err := newTodoistCredentialChecker(*todoistAPIKey).CheckCredentials(ctx); err != nil {
+		log.Warnf("Todoist backend health check failed: %v", err)
+		return healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	return healthpb.HealthCheckResponse_SERVING
+}
+
+// runBackendHealthChecks publishes backend credential status on healthServer right
+// away and then on every interval until ctx is done. An interval of 0 checks once.
+func runBackendHealthChecks(ctx context.Context, healthServer *health.Server, interval time.Duration) {
+	healthServer.SetServingStatus(todoistHealthService, checkTodoistBackend(ctx))
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			healthServer.SetServingStatus(todoistHealthService, checkTodoistBackend(ctx))
+		}
+	}
+}
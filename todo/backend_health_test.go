@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type fakeCredentialChecker struct {
+	err error
+}
+
+func (f fakeCredentialChecker) CheckCredentials(context.Context) error {
+	return f.err
+}
+
+func useCredentialChecker(t *testing.T, apiKey string, err error) {
+	t.Helper()
+	origKey := *todoistAPIKey
+	origFactory := newTodoistCredentialChecker
+	t.Cleanup(func() {
+		*todoistAPIKey = origKey
+		newTodoistCredentialChecker = origFactory
+	})
+
+	*todoistAPIKey = apiKey
+	newTodoistCredentialChecker = func(string) todoistCredentialChecker {
+		return fakeCredentialChecker{err: err}
+	}
+}
+
+func todoistHealthStatus(t *testing.T, healthServer *health.Server) healthpb.HealthCheckResponse_ServingStatus {
+	t.Helper()
+	resp, err := healthServer.Check(context.Background(), &healthpb.HealthCheckRequest{Service: todoistHealthService})
+	require.NoError(t, err)
+	return resp.Status
+}
+
+func TestRunBackendHealthChecks(t *testing.T) {
+	tests := []struct {
+		name   string
+		apiKey string
+		err    error
+		want   healthpb.HealthCheckResponse_ServingStatus
+	}{
+		{name: "healthy credentials", apiKey: testGenericAPIKey, want: healthpb.HealthCheckResponse_SERVING},
+		{
+			name:   "rejected credentials",
+			apiKey: testGenericAPIKey,
+			err:    errors.New("received non-2xx response status 401"),
+			want:   healthpb.HealthCheckResponse_NOT_SERVING,
+		},
+		{name: "missing api key", want: healthpb.HealthCheckResponse_NOT_SERVING},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useCredentialChecker(t, tt.apiKey, tt.err)
+			healthServer := health.NewServer()
+
+			runBackendHealthChecks(context.Background(), healthServer, 0)
+
+			assert.Equal(t, tt.want, todoistHealthStatus(t, healthServer))
+		})
+	}
+}
@@ -3,8 +3,12 @@
 /todo \
     -port=${PORT} \
     -todoist-api-key=${TODOIST_API_KEY} \
+    -todoist-api-key-file=${TODOIST_API_KEY_FILE} \
     -todoist-default-project-id=${TODOIST_DEFAULT_PROJECT_ID} \
+    "-todoist-project-name=${TODOIST_PROJECT_NAME}" \
+    -todoist-section-id=${TODOIST_SECTION_ID} \
     -todoist-base-url=${TODOIST_BASE_URL} \
+    -todoist-max-concurrent-requests=${TODOIST_MAX_CONCURRENT_REQUESTS:-0} \
     -dependency-reconcile-interval=${DEPENDENCY_RECONCILE_INTERVAL} \
     -dependency-bootstrap-interval=${DEPENDENCY_BOOTSTRAP_INTERVAL} \
     -dependency-grace-period=${DEPENDENCY_GRACE_PERIOD} \
@@ -12,4 +16,6 @@
     -dependency-read-timeout=${DEPENDENCY_READ_TIMEOUT} \
     -dependency-write-timeout=${DEPENDENCY_WRITE_TIMEOUT} \
     -dependency-enable-scheduler=${DEPENDENCY_ENABLE_SCHEDULER} \
-    -dependency-bootstrap-excluded-project-ids=${DEPENDENCY_BOOTSTRAP_EXCLUDED_PROJECT_IDS}
+    -dependency-bootstrap-excluded-project-ids=${DEPENDENCY_BOOTSTRAP_EXCLUDED_PROJECT_IDS} \
+    -grpc-auth-token=${GRPC_AUTH_TOKEN} \
+    -enable-reflection=${ENABLE_GRPC_REFLECTION:-true}
@@ -44,12 +44,33 @@ type Client struct {
 	baseURL    string
 
 	requestLimiter *utils.SlidingWindowLimiter
-	retryConfig    utils.RetryConfig
+	// concurrency bounds in-flight HTTP requests across every client sharing it; nil is unbounded.
+	concurrency *utils.Semaphore
+	retryConfig utils.RetryConfig
+}
+
+// ClientOption customizes a Client created by NewClient.
+type ClientOption func(*Client)
+
+// WithMaxAttempts sets how many times a request is tried before its error is returned.
+// Retries back off exponentially and stop early when the request context is done.
+func WithMaxAttempts(attempts int) ClientOption {
+	return func(c *Client) {
+		c.retryConfig.MaxAttempts = max(attempts, 1)
+	}
+}
+
+// WithConcurrency makes every request attempt hold a slot of sem while it talks to Todoist.
+// Share one semaphore between clients to bound the process-wide number of in-flight calls.
+func WithConcurrency(sem *utils.Semaphore) ClientOption {
+	return func(c *Client) {
+		c.concurrency = sem
+	}
 }
 
 // NewClient creates and returns a new Todoist API client.
-func NewClient(token string) *Client {
-	return &Client{
+func NewClient(token string, opts ...ClientOption) *Client {
+	client := &Client{
 		httpClient: &http.Client{
 			Timeout: defaultTimeout,
 		},
@@ -62,11 +83,15 @@ func NewClient(token string) *Client {
 			MaxDelay:    2 * time.Second,
 		},
 	}
+	for _, opt := range opts {
+		opt(client)
+	}
+	return client
 }
 
 // NewClientWithBaseURL creates a Todoist client with an optional base URL override.
-func NewClientWithBaseURL(token string, baseURL string) *Client {
-	client := NewClient(token)
+func NewClientWithBaseURL(token string, baseURL string, opts ...ClientOption) *Client {
+	client := NewClient(token, opts...)
 	if trimmedBaseURL := strings.TrimSpace(baseURL); trimmedBaseURL != "" {
 		normalizedBaseURL := strings.TrimRight(trimmedBaseURL, "/")
 		if normalizedBaseURL == "" {
@@ -93,6 +118,16 @@ type todoistHTTPError struct {
 	statusCode int
 	body       string
 	retryAfter time.Duration
+	// apiError is the decoded body when Todoist returned a structured error.
+	apiError *ErrorResponse
+}
+
+// Unwrap exposes the structured Todoist error, so callers can errors.As into *ErrorResponse.
+func (e *todoistHTTPError) Unwrap() error {
+	if e.apiError == nil {
+		return nil
+	}
+	return e.apiError
 }
 
 func (e *todoistHTTPError) Error() string {
@@ -133,6 +168,12 @@ func (c *Client) GetTask(ctx context.Context, taskID string) (*Task, error) {
 	return &task, nil
 }
 
+// CheckCredentials makes a minimal authenticated request to confirm the token is accepted.
+func (c *Client) CheckCredentials(ctx context.Context) error {
+	_, err := c.doRequest(ctx, http.MethodGet, todoistTasksPath+"?limit=1", nil, "")
+	return err
+}
+
 // ListActiveTasks lists active (not completed) tasks.
 func (c *Client) ListActiveTasks(ctx context.Context) ([]*Task, error) {
 	allTasks := make([]*Task, 0)
@@ -304,6 +345,55 @@ func (c *Client) EnsureLabels(ctx context.Context, labels []string) (*EnsureLabe
 	return result, nil
 }
 
+// ListProjects lists all Todoist projects.
+func (c *Client) ListProjects(ctx context.Context) ([]*Project, error) {
+	allProjects := make([]*Project, 0)
+	cursor := ""
+	for {
+		path := todoistProjectsPath
+		if cursor != "" {
+			path += "?cursor=" + url.QueryEscape(cursor)
+		}
+
+		body, err := c.doRequest(ctx, http.MethodGet, path, nil, "")
+		if err != nil {
+			return nil, err
+		}
+
+		pageProjects, nextCursor, parseErr := parseProjectPage(body)
+		if parseErr != nil {
+			return nil, parseErr
+		}
+		allProjects = append(allProjects, pageProjects...)
+		if nextCursor == "" {
+			break
+		}
+		cursor = nextCursor
+	}
+	return allProjects, nil
+}
+
+// CreateProject creates one Todoist project.
+func (c *Client) CreateProject(ctx context.Context, name string) (*Project, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("project name is required")
+	}
+
+	var project Project
+	if err := c.doJSON(
+		ctx,
+		http.MethodPost,
+		todoistProjectsPath,
+		&createProjectRequest{Name: name},
+		"",
+		&project,
+	); err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
 // doJSON executes an HTTP call and decodes JSON into out when the body is present.
 func (c *Client) doJSON(
 	ctx context.Context,
@@ -391,6 +481,12 @@ func (c *Client) doRequestOnce(
 		}
 	}
 
+	// Hold the slot per attempt, so retry backoff doesn't keep other requests waiting
+	if err := c.concurrency.Acquire(ctx); err != nil {
+		return nil, fmt.Errorf("todoist request not started while waiting for a concurrency slot: %w", err)
+	}
+	defer c.concurrency.Release()
+
 	var bodyReader io.Reader
 	if len(reqBodyBytes) > 0 {
 		bodyReader = bytes.NewReader(reqBodyBytes)
@@ -447,6 +543,7 @@ func (c *Client) doRequestOnce(
 			statusCode: resp.StatusCode,
 			body:       string(bodyBytes),
 			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+			apiError:   parseErrorResponse(bodyBytes, resp.StatusCode),
 		}
 	}
 
@@ -466,23 +563,45 @@ func shouldRetryRequest(err error) (bool, time.Duration) {
 				return true, httpErr.retryAfter
 			}
 			return true, 0
-		case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		case http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout:
 			return true, 0
 		default:
 			return false, 0
 		}
 	}
 
+	// An unknown host won't resolve on retry, but refused or reset connections often recover
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return dnsErr.IsTimeout || dnsErr.IsTemporary, 0
+	}
 	var netErr net.Error
 	if errors.As(err, &netErr) && netErr.Timeout() {
 		return true, 0
 	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return true, 0
+	}
 	if errors.Is(err, context.DeadlineExceeded) {
 		return true, 0
 	}
 	return false, 0
 }
 
+// parseErrorResponse decodes a Todoist error body, or returns nil when it isn't one.
+func parseErrorResponse(body []byte, statusCode int) *ErrorResponse {
+	var apiErr ErrorResponse
+	if err := json.Unmarshal(body, &apiErr); err != nil || apiErr.ErrorMessage == "" {
+		return nil
+	}
+	if apiErr.HTTPCode == 0 {
+		apiErr.HTTPCode = statusCode
+	}
+	return &apiErr
+}
+
 func parseRetryAfter(value string, now time.Time) time.Duration {
 	value = strings.TrimSpace(value)
 	if value == "" {
@@ -592,6 +711,31 @@ func parseLabelPage(body []byte) ([]*Label, string, error) {
 	return envelope.Results, envelope.NextCursor, nil
 }
 
+// parseProjectPage supports both legacy array and paginated envelope project responses.
+func parseProjectPage(body []byte) ([]*Project, string, error) {
+	trimmed := strings.TrimSpace(string(body))
+	if trimmed == "" {
+		return nil, "", nil
+	}
+
+	var pageProjects []*Project
+	if err := json.Unmarshal(body, &pageProjects); err == nil {
+		return pageProjects, "", nil
+	}
+
+	var envelope struct {
+		Results    []*Project `json:"results"`
+		NextCursor string     `json:"next_cursor"`
+	}
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, "", fmt.Errorf("failed to decode project list response: %w", err)
+	}
+	if !hasJSONField(body, "results") {
+		return nil, "", fmt.Errorf("failed to decode project list response: missing results field")
+	}
+	return envelope.Results, envelope.NextCursor, nil
+}
+
 // hasJSONField verifies that the decoded JSON object contains a named top-level field.
 func hasJSONField(body []byte, field string) bool {
 	var raw map[string]json.RawMessage
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"strings"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -164,6 +165,45 @@ func TestClient_EnsureLabels(t *testing.T) {
 	assert.Equal(t, 2, listCalls)
 }
 
+func TestClient_Projects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/projects":
+			switch r.URL.Query().Get("cursor") {
+			case "":
+				_, _ = w.Write([]byte(`{"results":[{"id":"1","name":"Inbox"}],"next_cursor":"cursor-1"}`))
+			case "cursor-1":
+				_, _ = w.Write([]byte(`{"results":[{"id":"2","name":"Email"}],"next_cursor":""}`))
+			default:
+				w.WriteHeader(http.StatusBadRequest)
+			}
+		case r.Method == http.MethodPost && r.URL.Path == "/projects":
+			var req createProjectRequest
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+			_ = json.NewEncoder(w).Encode(Project{ID: "3", Name: req.Name})
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient("token")
+	client.baseURL = server.URL
+
+	projects, err := client.ListProjects(context.Background())
+	require.NoError(t, err)
+	require.Len(t, projects, 2)
+	assert.Equal(t, "Inbox", projects[0].Name)
+	assert.Equal(t, "2", projects[1].ID)
+
+	created, err := client.CreateProject(context.Background(), " Todofy ")
+	require.NoError(t, err)
+	assert.Equal(t, &Project{ID: "3", Name: "Todofy"}, created)
+
+	_, err = client.CreateProject(context.Background(), " ")
+	assert.ErrorContains(t, err, "project name is required")
+}
+
 func TestClient_RequestCompliance(t *testing.T) {
 	t.Run("rejects oversized POST body before send", func(t *testing.T) {
 		var calls int32
@@ -259,3 +299,171 @@ func TestClient_RequestCompliance(t *testing.T) {
 		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
 	})
 }
+
+func TestClient_CreateTaskRetries(t *testing.T) {
+	newFastClient := func(baseURL string, attempts int) *Client {
+		client := NewClientWithBaseURL("token-retry-"+baseURL, baseURL, WithMaxAttempts(attempts))
+		client.retryConfig.BaseDelay = time.Millisecond
+		client.retryConfig.MaxDelay = 5 * time.Millisecond
+		return client
+	}
+
+	t.Run("succeeds after two transient failures", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "rid", r.Header.Get("X-Request-Id"), "retries reuse the idempotency key")
+			switch atomic.AddInt32(&calls, 1) {
+			case 1:
+				w.WriteHeader(http.StatusServiceUnavailable)
+			case 2:
+				w.WriteHeader(http.StatusInternalServerError)
+			default:
+				_ = json.NewEncoder(w).Encode(Task{ID: "ok", Content: "A"})
+			}
+		}))
+		defer server.Close()
+
+		client := newFastClient(server.URL, 3)
+		task, err := client.CreateTask(context.Background(), "rid", &CreateTaskRequest{Content: "A"})
+		require.NoError(t, err)
+		assert.Equal(t, "ok", task.ID)
+		assert.Equal(t, int32(3), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("returns the last error once attempts are exhausted", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusBadGateway)
+		}))
+		defer server.Close()
+
+		_, err := newFastClient(server.URL, 2).CreateTask(context.Background(), "rid", &CreateTaskRequest{Content: "A"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "502")
+		assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("retries refused connections", func(t *testing.T) {
+		server := httptest.NewServer(http.NotFoundHandler())
+		baseURL := server.URL
+		server.Close()
+
+		start := time.Now()
+		_, err := newFastClient(baseURL, 3).CreateTask(context.Background(), "rid", &CreateTaskRequest{Content: "A"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "failed to execute request")
+		assert.GreaterOrEqual(t, time.Since(start), 2*time.Millisecond, "backed off between attempts")
+	})
+
+	t.Run("does not retry client errors", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusBadRequest)
+		}))
+		defer server.Close()
+
+		_, err := newFastClient(server.URL, 3).CreateTask(context.Background(), "rid", &CreateTaskRequest{Content: "A"})
+		require.Error(t, err)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("stops retrying when the context is cancelled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			cancel()
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		client := NewClientWithBaseURL("token-retry-cancel", server.URL, WithMaxAttempts(5))
+		_, err := client.CreateTask(ctx, "rid", &CreateTaskRequest{Content: "A"})
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	})
+}
+
+func TestWithMaxAttempts(t *testing.T) {
+	assert.Equal(t, 3, NewClient("token-default-attempts").retryConfig.MaxAttempts)
+	assert.Equal(t, 5, NewClient("token-five-attempts", WithMaxAttempts(5)).retryConfig.MaxAttempts)
+	assert.Equal(t, 1, NewClient("token-zero-attempts", WithMaxAttempts(0)).retryConfig.MaxAttempts)
+}
+
+func TestWithConcurrency(t *testing.T) {
+	t.Run("bounds in-flight requests across clients and endpoints", func(t *testing.T) {
+		var active, maxActive, calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			current := atomic.AddInt32(&active, 1)
+			for {
+				observed := atomic.LoadInt32(&maxActive)
+				if current <= observed || atomic.CompareAndSwapInt32(&maxActive, observed, current) {
+					break
+				}
+			}
+			time.Sleep(30 * time.Millisecond)
+			atomic.AddInt32(&active, -1)
+			if r.URL.Path == "/projects" && r.Method == http.MethodGet {
+				_, _ = w.Write([]byte(`{"results":[],"next_cursor":""}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"id":"1"}`))
+		}))
+		defer server.Close()
+
+		const limit = 2
+		sem := utils.NewSemaphore(limit)
+		var wg sync.WaitGroup
+		errs := make(chan error, 6)
+		for i := 0; i < 6; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				client := NewClientWithBaseURL("token-concurrency", server.URL, WithConcurrency(sem))
+				var err error
+				switch i % 3 {
+				case 0:
+					_, err = client.CreateTask(context.Background(), "", &CreateTaskRequest{Content: "task"})
+				case 1:
+					_, err = client.ListProjects(context.Background())
+				default:
+					_, err = client.CreateProject(context.Background(), "Todofy")
+				}
+				errs <- err
+			}(i)
+		}
+		wg.Wait()
+		close(errs)
+
+		for err := range errs {
+			assert.NoError(t, err)
+		}
+		assert.LessOrEqual(t, atomic.LoadInt32(&maxActive), int32(limit))
+		assert.Equal(t, int32(6), atomic.LoadInt32(&calls))
+	})
+
+	t.Run("gives up waiting for a slot when the context is done", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			_, _ = w.Write([]byte(`{"id":"1"}`))
+		}))
+		defer server.Close()
+
+		sem := utils.NewSemaphore(1)
+		// Hold the only slot so the call has to wait.
+		require.NoError(t, sem.Acquire(context.Background()))
+		defer sem.Release()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		client := NewClientWithBaseURL("token-concurrency-ctx", server.URL, WithConcurrency(sem))
+		_, err := client.CreateTask(ctx, "", &CreateTaskRequest{Content: "queued"})
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Zero(t, atomic.LoadInt32(&calls))
+	})
+}
@@ -3,6 +3,7 @@ package todoist
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -12,6 +13,37 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
+func TestClient_CheckCredentials(t *testing.T) {
+	t.Run("accepted token", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, http.MethodGet, r.Method)
+			assert.Equal(t, "/tasks", r.URL.Path)
+			assert.Equal(t, "1", r.URL.Query().Get("limit"))
+			assert.Equal(t, "Bearer test-token", r.Header.Get("Authorization"))
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"results":[],"next_cursor":null}`))
+		}))
+		defer server.Close()
+
+		client := NewClient("test-token")
+		client.baseURL = server.URL
+		assert.NoError(t, client.CheckCredentials(context.Background()))
+	})
+
+	t.Run("rejected token", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}))
+		defer server.Close()
+
+		client := NewClient("bad-token")
+		client.baseURL = server.URL
+		err := client.CheckCredentials(context.Background())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "401")
+	})
+}
+
 func TestClient_CreateTask(t *testing.T) {
 	t.Run("successful task creation", func(t *testing.T) {
 		// Mock server
@@ -57,6 +89,49 @@ func TestClient_CreateTask(t *testing.T) {
 		assert.Equal(t, "123", result.ProjectID)
 	})
 
+	t.Run("section and parent ids serialize when set", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]any
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.Equal(t, "sec-1", body["section_id"])
+			assert.Equal(t, "parent-2", body["parent_id"])
+
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(Task{ID: "456", SectionID: "sec-1", ParentID: "parent-2"})
+		}))
+		defer server.Close()
+
+		client := NewClient("test-token")
+		client.baseURL = server.URL
+
+		result, err := client.CreateTask(context.Background(), "req-123", &CreateTaskRequest{
+			Content:   "Subtask",
+			SectionID: "sec-1",
+			ParentID:  "parent-2",
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "456", result.ID)
+	})
+
+	t.Run("section and parent ids are omitted when empty", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var body map[string]any
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+			assert.NotContains(t, body, "section_id")
+			assert.NotContains(t, body, "parent_id")
+
+			w.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(w).Encode(Task{ID: "456"})
+		}))
+		defer server.Close()
+
+		client := NewClient("test-token")
+		client.baseURL = server.URL
+
+		_, err := client.CreateTask(context.Background(), "req-123", &CreateTaskRequest{Content: "Task"})
+		require.NoError(t, err)
+	})
+
 	t.Run("API error response", func(t *testing.T) {
 		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 			w.WriteHeader(http.StatusBadRequest)
@@ -79,6 +154,60 @@ func TestClient_CreateTask(t *testing.T) {
 		assert.Contains(t, err.Error(), "400")
 	})
 
+	t.Run("structured error body is returned as ErrorResponse", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"error": "Project is archived", "error_code": 31, "http_code": 403}`))
+		}))
+		defer server.Close()
+
+		client := NewClient("test-token")
+		client.baseURL = server.URL
+
+		_, err := client.CreateTask(context.Background(), "req-123", &CreateTaskRequest{Content: "Test Task"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "403")
+
+		var apiErr *ErrorResponse
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, "Project is archived", apiErr.ErrorMessage)
+		assert.Equal(t, 31, apiErr.ErrorCode)
+		assert.Equal(t, http.StatusForbidden, apiErr.HTTPCode)
+	})
+
+	t.Run("http code defaults to the response status", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error": "Invalid request"}`))
+		}))
+		defer server.Close()
+
+		client := NewClient("test-token")
+		client.baseURL = server.URL
+
+		_, err := client.CreateTask(context.Background(), "req-123", &CreateTaskRequest{Content: "Test Task"})
+		var apiErr *ErrorResponse
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusBadRequest, apiErr.HTTPCode)
+	})
+
+	t.Run("unstructured error body falls back to the raw body", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte("Bad Request"))
+		}))
+		defer server.Close()
+
+		client := NewClient("test-token")
+		client.baseURL = server.URL
+
+		_, err := client.CreateTask(context.Background(), "req-123", &CreateTaskRequest{Content: "Test Task"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "Bad Request")
+		var apiErr *ErrorResponse
+		assert.False(t, errors.As(err, &apiErr))
+	})
+
 	t.Run("network error", func(t *testing.T) {
 		client := NewClient("test-token")
 		client.baseURL = "http://non-existent-server"
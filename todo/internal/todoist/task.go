@@ -6,11 +6,15 @@ const (
 	defaultBaseURL    = todoistapi.DefaultBaseURL
 	todoistTasksPath  = todoistapi.TasksPath
 	todoistLabelsPath = todoistapi.LabelsPath
+
+	todoistProjectsPath = todoistapi.ProjectsPath
 )
 
 type CreateTaskRequest = todoistapi.CreateTaskRequest
 type UpdateTaskRequest = todoistapi.UpdateTaskRequest
 type Label = todoistapi.Label
 type createLabelRequest = todoistapi.CreateLabelRequest
+type Project = todoistapi.Project
+type createProjectRequest = todoistapi.CreateProjectRequest
 type EnsureLabelsResult = todoistapi.EnsureLabelsResult
 type Task = todoistapi.Task
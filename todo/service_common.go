@@ -22,7 +22,7 @@ type todoistOperationalClient interface {
 type todoistOperationalClientFactory func(apiKey string) todoistOperationalClient
 
 func defaultTodoistOperationalClientFactory(apiKey string) todoistOperationalClient {
-	return todoist.NewClientWithBaseURL(apiKey, *todoistBaseURL)
+	return todoist.NewClientWithBaseURL(apiKey, *todoistBaseURL, todoist.WithConcurrency(todoistConcurrency))
 }
 
 // normalizedTaskFromTodoistTask strips metadata syntax and returns API-facing task fields.
@@ -7,23 +7,23 @@ import (
 	"flag"
 	"fmt"
 	"net"
+	"os"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
-	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/health"
-	healthpb "google.golang.org/grpc/health/grpc_health_v1"
-	"google.golang.org/grpc/reflection"
 	"google.golang.org/grpc/status"
 
 	pb "github.com/ziyixi/protos/go/todofy"
 	"github.com/ziyixi/todofy/todo/internal/todoist"
+	"github.com/ziyixi/todofy/utils"
 )
 
 var log = logrus.New()
 var GitCommit string // Will be set by Bazel at build time
+var BuildDate string // Will be set via -ldflags at build time
 
 // initLogger initializes the logger configuration
 func initLogger() {
@@ -33,20 +33,52 @@ func initLogger() {
 }
 
 var (
-	port = flag.Int("port", 50052, "The server port of the Todo service")
+	showVersion      = flag.Bool("version", false, "Print version information and exit")
+	port             = flag.Int("port", 50052, "The server port of the Todo service")
+	enableReflection = flag.Bool("enable-reflection", true, "Register the gRPC reflection service")
+	grpcAuthToken    = flag.String(
+		"grpc-auth-token", "", "Shared secret callers must send as a bearer token (empty disables auth)",
+	)
 
 	// Todoist API credentials
-	todoistAPIKey           = flag.String("todoist-api-key", "", "The API key for Todoist")
+	todoistAPIKey     = flag.String("todoist-api-key", "", "The API key for Todoist")
+	todoistAPIKeyFile = flag.String(
+		"todoist-api-key-file",
+		"",
+		"File containing the Todoist API key (--todoist-api-key takes precedence)",
+	)
 	todoistDefaultProjectID = flag.String(
 		"todoist-default-project-id",
 		"",
 		"Default Todoist project ID for created tasks",
 	)
+	todoistProjectName = flag.String(
+		"todoist-project-name",
+		"",
+		"Project to create tasks in when --todoist-default-project-id is unset or no longer exists; "+
+			"created if missing",
+	)
+	todoistSectionID = flag.String(
+		"todoist-section-id",
+		"",
+		"Default Todoist section ID for created tasks (must belong to the task's project)",
+	)
 	todoistBaseURL = flag.String(
 		"todoist-base-url",
 		"",
 		"Override base URL for the Todoist API",
 	)
+	todoistMaxConcurrentRequests = flag.Int(
+		"todoist-max-concurrent-requests",
+		0,
+		"Maximum concurrent Todoist API calls across the todo service's RPCs; extra calls queue (0 = unlimited)",
+	)
+
+	backendHealthInterval = flag.Duration(
+		"backend-health-interval",
+		5*time.Minute,
+		"How often to re-validate backend credentials for the per-backend health status (0 = check once)",
+	)
 
 	dependencyReconcileInterval = flag.Duration(
 		"dependency-reconcile-interval",
@@ -90,14 +122,28 @@ var (
 	)
 )
 
+// todoistConcurrency is shared by every Todoist client the RPCs create, bounding their in-flight
+// API calls together; set from --todoist-max-concurrent-requests.
+var todoistConcurrency *utils.Semaphore
+
 // todoistTaskCreator abstracts the Todoist task creation API for testing.
 type todoistTaskCreator interface {
 	CreateTask(ctx context.Context, requestID string, taskDetails *todoist.CreateTaskRequest) (*todoist.Task, error)
 }
 
+// todoistProjectManager is implemented by Todoist clients that can look up and create projects,
+// which --todoist-project-name needs.
+type todoistProjectManager interface {
+	ListProjects(ctx context.Context) ([]*todoist.Project, error)
+	CreateProject(ctx context.Context, name string) (*todoist.Project, error)
+}
+
 type todoServer struct {
 	pb.UnimplementedTodoServiceServer
 	newTodoistClient func(apiKey string) todoistTaskCreator
+	// projectMu guards projectID, the project resolved for --todoist-project-name.
+	projectMu sync.Mutex
+	projectID string
 }
 
 const (
@@ -133,7 +179,7 @@ func (s *todoServer) PopulateTodoByTodoist(ctx context.Context, req *pb.TodoRequ
 	factory := s.newTodoistClient
 	if factory == nil {
 		factory = func(apiKey string) todoistTaskCreator {
-			return todoist.NewClientWithBaseURL(apiKey, *todoistBaseURL)
+			return todoist.NewClientWithBaseURL(apiKey, *todoistBaseURL, todoist.WithConcurrency(todoistConcurrency))
 		}
 	}
 	client := factory(*todoistAPIKey)
@@ -148,6 +194,16 @@ func (s *todoServer) PopulateTodoByTodoist(ctx context.Context, req *pb.TodoRequ
 	if projectID := *todoistDefaultProjectID; projectID != "" {
 		taskRequest.ProjectID = projectID
 	}
+	if *todoistProjectName != "" {
+		projectID, err := s.resolveProjectID(ctx, client)
+		if err != nil {
+			return nil, fmt.Errorf("failed to resolve Todoist project %q: %w", *todoistProjectName, err)
+		}
+		taskRequest.ProjectID = projectID
+	}
+	if sectionID := *todoistSectionID; sectionID != "" {
+		taskRequest.SectionID = sectionID
+	}
 
 	// Use a deterministic request ID so retries do not create duplicate Todoist tasks.
 	requestID := buildTodoistRequestID(req)
@@ -155,6 +211,8 @@ func (s *todoServer) PopulateTodoByTodoist(ctx context.Context, req *pb.TodoRequ
 	// Create the task.
 	task, err := client.CreateTask(ctx, requestID, taskRequest)
 	if err != nil {
+		// The resolved project may have been deleted since, so look it up again next time
+		s.forgetProjectID()
 		return nil, fmt.Errorf("failed to create task in Todoist: %w", err)
 	}
 
@@ -166,6 +224,60 @@ func (s *todoServer) PopulateTodoByTodoist(ctx context.Context, req *pb.TodoRequ
 	}, nil
 }
 
+// resolveProjectID returns the project tasks go to under --todoist-project-name: the configured
+// --todoist-default-project-id while it still exists, else the project with that name, created
+// when missing. The result is cached until a task creation fails.
+func (s *todoServer) resolveProjectID(ctx context.Context, client todoistTaskCreator) (string, error) {
+	s.projectMu.Lock()
+	defer s.projectMu.Unlock()
+	if s.projectID != "" {
+		return s.projectID, nil
+	}
+
+	projects, ok := client.(todoistProjectManager)
+	if !ok {
+		return "", fmt.Errorf("todoist client %T cannot manage projects", client)
+	}
+	existing, err := projects.ListProjects(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to list projects: %w", err)
+	}
+
+	var byName string
+	for _, project := range existing {
+		if project == nil {
+			continue
+		}
+		if project.ID != "" && project.ID == *todoistDefaultProjectID {
+			s.projectID = project.ID
+			return s.projectID, nil
+		}
+		if byName == "" && project.Name == *todoistProjectName {
+			byName = project.ID
+		}
+	}
+	if byName == "" {
+		created, err := projects.CreateProject(ctx, *todoistProjectName)
+		if err != nil {
+			return "", fmt.Errorf("failed to create project: %w", err)
+		}
+		log.Infof("Created Todoist project %q (ID: %s)", created.Name, created.ID)
+		byName = created.ID
+	} else if *todoistDefaultProjectID != "" {
+		log.Warnf("Todoist project %s not found, using project %q (ID: %s)",
+			*todoistDefaultProjectID, *todoistProjectName, byName)
+	}
+	s.projectID = byName
+	return s.projectID, nil
+}
+
+// forgetProjectID drops the cached --todoist-project-name project.
+func (s *todoServer) forgetProjectID() {
+	s.projectMu.Lock()
+	defer s.projectMu.Unlock()
+	s.projectID = ""
+}
+
 func buildTodoistRequestID(req *pb.TodoRequest) string {
 	hashInput := strings.Join([]string{
 		req.GetSubject(),
@@ -181,34 +293,54 @@ func main() {
 	initLogger()
 	flag.Parse()
 
+	if *showVersion {
+		utils.PrintVersion(os.Stdout, "todofy-todo", GitCommit, BuildDate)
+		return
+	}
+	if err := resolveAPIKeyFlags(); err != nil {
+		log.Fatalf("%v", err)
+	}
+
 	if err := runGRPCServer(); err != nil {
 		log.Fatalf("server error: %v", err)
 	}
 }
 
+// resolveAPIKeyFlags fills --todoist-api-key from --todoist-api-key-file when only the file is given.
+func resolveAPIKeyFlags() error {
+	key, err := utils.ResolveSecret(*todoistAPIKey, *todoistAPIKeyFile)
+	if err != nil {
+		return fmt.Errorf("invalid todoist-api-key-file: %w", err)
+	}
+	*todoistAPIKey = key
+	return nil
+}
+
 func runGRPCServer() error {
 	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", *port))
 	if err != nil {
 		return fmt.Errorf("failed to listen: %w", err)
 	}
 
-	server := grpc.NewServer()
+	server, healthServer := utils.NewGRPCServer(
+		utils.SharedSecretServerOption(*grpcAuthToken),
+		utils.WithReflection(*enableReflection),
+		utils.WithRPCLogger(log),
+	)
+	todoistConcurrency = utils.NewSemaphore(*todoistMaxConcurrentRequests)
 	todoSvc := &todoServer{}
+	log.Infof("Max concurrent Todoist requests: %d (0 = unlimited)", *todoistMaxConcurrentRequests)
 	todoistSvc := &todoistServer{}
 	dependencySvc := newDependencyServer()
 
 	pb.RegisterTodoServiceServer(server, todoSvc)
 	pb.RegisterTodoistServiceServer(server, todoistSvc)
 	pb.RegisterDependencyServiceServer(server, dependencySvc)
-	reflection.Register(server)
-
-	healthServer := health.NewServer()
-	healthpb.RegisterHealthServer(server, healthServer)
-	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
 
 	backgroundCtx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	dependencySvc.StartBackgroundReconcile(backgroundCtx)
+	go runBackendHealthChecks(backgroundCtx, healthServer, *backendHealthInterval)
 
 	log.Infof("Todo gRPC server is running on port %d", *port)
 	if err := server.Serve(lis); err != nil {
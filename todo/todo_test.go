@@ -2,10 +2,18 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	pb "github.com/ziyixi/protos/go/todofy"
 	"github.com/ziyixi/todofy/todo/internal/todoist"
 )
@@ -111,9 +119,11 @@ func TestPopulateTodoByTodoist_DI(t *testing.T) {
 	saveTodoistFlags := func() func() {
 		origKey := *todoistAPIKey
 		origDefaultProject := *todoistDefaultProjectID
+		origSection := *todoistSectionID
 		return func() {
 			*todoistAPIKey = origKey
 			*todoistDefaultProjectID = origDefaultProject
+			*todoistSectionID = origSection
 		}
 	}
 
@@ -215,6 +225,30 @@ func TestPopulateTodoByTodoist_DI(t *testing.T) {
 		assert.Equal(t, "task-456", resp.Id)
 		mockCreator.AssertExpectations(t)
 	})
+
+	t.Run("adds section id when configured", func(t *testing.T) {
+		defer saveTodoistFlags()()
+		*todoistAPIKey = testGenericAPIKey
+		*todoistDefaultProjectID = "proj-456"
+		*todoistSectionID = "sec-789"
+
+		mockCreator := new(mockTodoistTaskCreator)
+		mockCreator.On("CreateTask", mock.Anything, mock.Anything,
+			mock.MatchedBy(func(req *todoist.CreateTaskRequest) bool {
+				return req.ProjectID == "proj-456" && req.SectionID == "sec-789"
+			}),
+		).Return(&todoist.Task{ID: "task-789"}, nil)
+
+		server := &todoServer{
+			newTodoistClient: func(apiKey string) todoistTaskCreator {
+				return mockCreator
+			},
+		}
+
+		_, err := server.PopulateTodoByTodoist(context.Background(), &pb.TodoRequest{Subject: "Sectioned"})
+		assert.NoError(t, err)
+		mockCreator.AssertExpectations(t)
+	})
 }
 
 func TestBuildTodoistRequestID(t *testing.T) {
@@ -237,3 +271,116 @@ func TestBuildTodoistRequestID(t *testing.T) {
 	assert.Len(t, first, len(todoistRequestIDPrefix)+todoistRequestIDHashSize)
 	assert.Contains(t, first, todoistRequestIDPrefix)
 }
+
+func TestResolveAPIKeyFlags(t *testing.T) {
+	originalKey, originalFile := *todoistAPIKey, *todoistAPIKeyFile
+	t.Cleanup(func() {
+		*todoistAPIKey, *todoistAPIKeyFile = originalKey, originalFile
+	})
+
+	path := filepath.Join(t.TempDir(), "todoist-key")
+	require.NoError(t, os.WriteFile(path, []byte("file-token\n"), 0o600))
+
+	*todoistAPIKey, *todoistAPIKeyFile = "", path
+	require.NoError(t, resolveAPIKeyFlags())
+	assert.Equal(t, "file-token", *todoistAPIKey)
+
+	*todoistAPIKey, *todoistAPIKeyFile = "inline-token", path
+	require.NoError(t, resolveAPIKeyFlags())
+	assert.Equal(t, "inline-token", *todoistAPIKey)
+}
+
+// fakeTodoistProjectsServer serves the Todoist project and task endpoints, starting with projects.
+type fakeTodoistProjectsServer struct {
+	mu             sync.Mutex
+	projects       []todoist.Project
+	listCalls      int
+	createdNames   []string
+	taskProjectIDs []string
+}
+
+func (f *fakeTodoistProjectsServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/projects":
+		f.listCalls++
+		_ = json.NewEncoder(w).Encode(f.projects)
+	case r.Method == http.MethodPost && r.URL.Path == "/projects":
+		var req struct{ Name string }
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		project := todoist.Project{ID: fmt.Sprintf("created-%d", len(f.projects)+1), Name: req.Name}
+		f.projects = append(f.projects, project)
+		f.createdNames = append(f.createdNames, req.Name)
+		_ = json.NewEncoder(w).Encode(project)
+	case r.Method == http.MethodPost && r.URL.Path == "/tasks":
+		var req todoist.CreateTaskRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		f.taskProjectIDs = append(f.taskProjectIDs, req.ProjectID)
+		_ = json.NewEncoder(w).Encode(todoist.Task{ID: "task-1", Content: req.Content})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func TestPopulateTodoByTodoist_ProjectName(t *testing.T) {
+	originalKey, originalProject, originalName := *todoistAPIKey, *todoistDefaultProjectID, *todoistProjectName
+	t.Cleanup(func() {
+		*todoistAPIKey, *todoistDefaultProjectID, *todoistProjectName = originalKey, originalProject, originalName
+	})
+	*todoistAPIKey = testGenericAPIKey
+	*todoistProjectName = "Email"
+
+	newServer := func(fake *fakeTodoistProjectsServer) *todoServer {
+		api := httptest.NewServer(fake)
+		t.Cleanup(api.Close)
+		return &todoServer{newTodoistClient: func(apiKey string) todoistTaskCreator {
+			return todoist.NewClientWithBaseURL(apiKey, api.URL)
+		}}
+	}
+
+	t.Run("creates the missing project once and caches its ID", func(t *testing.T) {
+		*todoistDefaultProjectID = "deleted-project"
+		fake := &fakeTodoistProjectsServer{projects: []todoist.Project{{ID: "inbox", Name: "Inbox"}}}
+		server := newServer(fake)
+
+		for _, subject := range []string{"first", "second"} {
+			_, err := server.PopulateTodoByTodoist(context.Background(), &pb.TodoRequest{Subject: subject})
+			require.NoError(t, err)
+		}
+		assert.Equal(t, []string{"Email"}, fake.createdNames)
+		assert.Equal(t, 1, fake.listCalls)
+		assert.Equal(t, []string{"created-2", "created-2"}, fake.taskProjectIDs)
+	})
+
+	t.Run("keeps the configured project while it exists", func(t *testing.T) {
+		*todoistDefaultProjectID = "work"
+		fake := &fakeTodoistProjectsServer{projects: []todoist.Project{{ID: "work", Name: "Work"}}}
+		server := newServer(fake)
+
+		_, err := server.PopulateTodoByTodoist(context.Background(), &pb.TodoRequest{Subject: "task"})
+		require.NoError(t, err)
+		assert.Empty(t, fake.createdNames)
+		assert.Equal(t, []string{"work"}, fake.taskProjectIDs)
+	})
+
+	t.Run("reuses an existing project with the name", func(t *testing.T) {
+		*todoistDefaultProjectID = ""
+		fake := &fakeTodoistProjectsServer{projects: []todoist.Project{{ID: "email", Name: "Email"}}}
+		server := newServer(fake)
+
+		_, err := server.PopulateTodoByTodoist(context.Background(), &pb.TodoRequest{Subject: "task"})
+		require.NoError(t, err)
+		assert.Empty(t, fake.createdNames)
+		assert.Equal(t, []string{"email"}, fake.taskProjectIDs)
+	})
+
+	t.Run("fails when the client cannot manage projects", func(t *testing.T) {
+		*todoistDefaultProjectID = ""
+		server := &todoServer{newTodoistClient: func(string) todoistTaskCreator { return new(mockTodoistTaskCreator) }}
+
+		_, err := server.PopulateTodoByTodoist(context.Background(), &pb.TodoRequest{Subject: "task"})
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot manage projects")
+	})
+}
@@ -4,6 +4,7 @@ const (
 	DefaultBaseURL = "https://api.todoist.com/api/v1"
 	TasksPath      = "/tasks"
 	LabelsPath     = "/labels"
+	ProjectsPath   = "/projects"
 )
 
 // CreateTaskRequest represents the JSON payload for creating a new task.
@@ -41,6 +42,17 @@ type CreateLabelRequest struct {
 	Name string `json:"name"`
 }
 
+// Project represents a Todoist project.
+type Project struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// CreateProjectRequest represents the JSON payload for creating a project.
+type CreateProjectRequest struct {
+	Name string `json:"name"`
+}
+
 // EnsureLabelsResult reports ensure-label outcomes.
 type EnsureLabelsResult struct {
 	ExistingLabels []string
@@ -3,9 +3,11 @@
 package utils
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 
+	"github.com/PuerkitoBio/goquery"
 	"github.com/tidwall/gjson"
 
 	md "github.com/JohannesKaufmann/html-to-markdown"
@@ -20,16 +22,57 @@ const (
 
 // MailInfo is the struct to store the parsed email information
 type MailInfo struct {
-	From    string // headers.from
-	To      string // headers.to
-	Date    string // headers.date
-	Subject string // headers.subject
-	Content string // md(html)
+	From        string       // headers.from
+	To          string       // headers.to
+	Date        string       // headers.date
+	Subject     string       // headers.subject
+	Content     string       // md(html)
+	MessageID   string       // headers.message_id, without angle brackets
+	Attachments []Attachment // attachments
+}
+
+// Attachment describes one attachment of the parsed email. URL is only set when
+// cloudmailin stores attachments externally instead of embedding their content.
+type Attachment struct {
+	FileName    string // attachments[].file_name
+	ContentType string // attachments[].content_type
+	URL         string // attachments[].url
+}
+
+// Header line prefixes of the LLM input text built by MailInputText. The database service
+// reads the subject and sender back from the stored text with them.
+const (
+	MailSubjectPrefix = "SUBJECT:"
+	MailFromPrefix    = "FROM:"
+	MailToPrefix      = "TO:"
+)
+
+// MailInputText labels the email body with its subject, sender and recipient so the
+// model can tell who asked whom for what, which matters most for forwarded chains.
+// The header lines end at the first blank line.
+func MailInputText(info MailInfo) string {
+	return fmt.Sprintf("%s %s\n%s %s\n%s %s\n\nCONTENT:\n%s",
+		MailSubjectPrefix, info.Subject, MailFromPrefix, info.From, MailToPrefix, info.To, info.Content)
+}
+
+// ParseOptions tunes ParseCloudmailinWithOptions.
+type ParseOptions struct {
+	// KeepURLs leaves link targets in the content instead of stripping them to save tokens.
+	KeepURLs bool
+	// DropImages removes images instead of converting them to markdown image links.
+	DropImages bool
+	// FlattenTables renders each table row as one line of " | "-separated cell text.
+	FlattenTables bool
 }
 
 // ParseCloudmailin parses the cloudmailin email content
 func ParseCloudmailin(s string) MailInfo {
-	converter := md.NewConverter("", true, nil)
+	return ParseCloudmailinWithOptions(s, ParseOptions{})
+}
+
+// ParseCloudmailinWithOptions parses the cloudmailin email content according to opts.
+func ParseCloudmailinWithOptions(s string, opts ParseOptions) MailInfo {
+	converter := newMarkdownConverter(opts)
 	html := gjson.Get(s, "html").String()
 
 	// convert html to markdown
@@ -40,9 +83,12 @@ func ParseCloudmailin(s string) MailInfo {
 	}
 
 	// remove all urls, otherwise there will be too many tokens for next-step processing
-	urlPattern := `\(\s*https[^()]*\)`
-	m := regexp.MustCompile(urlPattern)
-	markdown := m.ReplaceAllString(markdownRaw, "()")
+	markdown := markdownRaw
+	if !opts.KeepURLs {
+		urlPattern := `\(\s*https[^()]*\)`
+		m := regexp.MustCompile(urlPattern)
+		markdown = m.ReplaceAllString(markdownRaw, "()")
+	}
 
 	// Truncate content to limit token consumption for LLM processing
 	if len(markdown) > maxEmailContentLength {
@@ -50,11 +96,13 @@ func ParseCloudmailin(s string) MailInfo {
 	}
 
 	res := MailInfo{
-		From:    gjson.Get(s, "headers.from").String(),
-		To:      gjson.Get(s, "headers.to").String(),
-		Date:    gjson.Get(s, "headers.date").String(),
-		Subject: gjson.Get(s, "headers.subject").String(),
-		Content: markdown,
+		From:        gjson.Get(s, "headers.from").String(),
+		To:          gjson.Get(s, "headers.to").String(),
+		Date:        gjson.Get(s, "headers.date").String(),
+		Subject:     gjson.Get(s, "headers.subject").String(),
+		Content:     markdown,
+		MessageID:   strings.Trim(gjson.Get(s, "headers.message_id").String(), "<>"),
+		Attachments: parseAttachments(s),
 	}
 
 	// Outlook email subject may have a prefix FW:
@@ -79,3 +127,55 @@ func ParseCloudmailin(s string) MailInfo {
 
 	return res
 }
+
+// newMarkdownConverter builds the html-to-markdown converter for opts.
+func newMarkdownConverter(opts ParseOptions) *md.Converter {
+	converter := md.NewConverter("", true, nil)
+	if opts.DropImages {
+		// Remove only applies to tags without a rule, and commonmark has one for img
+		converter.AddRules(md.Rule{
+			Filter: []string{"img"},
+			Replacement: func(_ string, _ *goquery.Selection, _ *md.Options) *string {
+				return md.String("")
+			},
+		})
+	}
+	if opts.FlattenTables {
+		converter.AddRules(md.Rule{
+			Filter: []string{"tr"},
+			Replacement: func(_ string, selec *goquery.Selection, _ *md.Options) *string {
+				var cells []string
+				selec.ChildrenFiltered("th, td").Each(func(_ int, cell *goquery.Selection) {
+					if text := strings.Join(strings.Fields(cell.Text()), " "); text != "" {
+						cells = append(cells, text)
+					}
+				})
+				return md.String(strings.Join(cells, " | ") + "\n")
+			},
+		}, md.Rule{
+			Filter: []string{"table"},
+			Replacement: func(content string, _ *goquery.Selection, _ *md.Options) *string {
+				return md.String("\n\n" + content + "\n\n")
+			},
+		})
+	}
+	return converter
+}
+
+// parseAttachments extracts attachment metadata, leaving embedded content out.
+func parseAttachments(s string) []Attachment {
+	var attachments []Attachment
+	for _, a := range gjson.Get(s, "attachments").Array() {
+		fileName := a.Get("file_name").String()
+		url := a.Get("url").String()
+		if fileName == "" && url == "" {
+			continue
+		}
+		attachments = append(attachments, Attachment{
+			FileName:    fileName,
+			ContentType: a.Get("content_type").String(),
+			URL:         url,
+		})
+	}
+	return attachments
+}
@@ -232,3 +232,79 @@ func TestMailInfo_Struct(t *testing.T) {
 	require.Equal(t, "Test", info.Subject)
 	require.Equal(t, "Content", info.Content)
 }
+
+func TestMailInputText(t *testing.T) {
+	info := MailInfo{From: "Ana <ana@example.com>", To: "me@example.com", Subject: "Review", Content: "body"}
+	assert.Equal(t, "SUBJECT: Review\nFROM: Ana <ana@example.com>\nTO: me@example.com\n\nCONTENT:\nbody",
+		MailInputText(info))
+}
+
+func TestParseCloudmailin_MessageIDAndAttachments(t *testing.T) {
+	input := `{
+		"headers": {
+			"from": "sender@example.com",
+			"to": "recipient@example.com",
+			"subject": "With files",
+			"message_id": "<abc123@mail.example.com>"
+		},
+		"plain": "See attached",
+		"attachments": [
+			{
+				"file_name": "report.pdf",
+				"content_type": "application/pdf",
+				"url": "https://files.example.com/report.pdf"
+			},
+			{"file_name": "inline.png", "content_type": "image/png", "content": "aGVsbG8="},
+			{"content_type": "text/plain"}
+		]
+	}`
+
+	result := ParseCloudmailin(input)
+
+	assert.Equal(t, "abc123@mail.example.com", result.MessageID)
+	assert.Equal(t, []Attachment{
+		{FileName: "report.pdf", ContentType: "application/pdf", URL: "https://files.example.com/report.pdf"},
+		{FileName: "inline.png", ContentType: "image/png"},
+	}, result.Attachments)
+}
+
+func TestParseCloudmailinWithOptions_URLStripping(t *testing.T) {
+	input := `{
+		"headers": {"from": "sender@example.com", "to": "recipient@example.com", "subject": "Shared doc"},
+		"html": "<p>Open <a href=\"https://docs.example.com/d/123\">the doc</a></p>"
+	}`
+
+	stripped := ParseCloudmailinWithOptions(input, ParseOptions{})
+	assert.Equal(t, "Open [the doc]()", stripped.Content)
+	assert.Equal(t, stripped, ParseCloudmailin(input), "stripping is the default")
+
+	kept := ParseCloudmailinWithOptions(input, ParseOptions{KeepURLs: true})
+	assert.Equal(t, "Open [the doc](https://docs.example.com/d/123)", kept.Content)
+}
+
+func TestParseCloudmailinWithOptions_DropImages(t *testing.T) {
+	input := `{
+		"headers": {"from": "sender@example.com", "to": "recipient@example.com", "subject": "Newsletter"},
+		"html": "<p><img src=\"https://cdn.example.com/logo.png\" alt=\"logo\"> Weekly update</p>"
+	}`
+
+	kept := ParseCloudmailinWithOptions(input, ParseOptions{KeepURLs: true})
+	assert.Contains(t, kept.Content, "![logo](https://cdn.example.com/logo.png)")
+
+	dropped := ParseCloudmailinWithOptions(input, ParseOptions{KeepURLs: true, DropImages: true})
+	assert.Equal(t, "Weekly update", dropped.Content)
+}
+
+func TestParseCloudmailinWithOptions_FlattenTables(t *testing.T) {
+	input := `{
+		"headers": {"from": "sender@example.com", "to": "recipient@example.com", "subject": "Receipt"},
+		"html": "<p>Your order</p><table><tr><th>Item</th><th>Price</th></tr>` +
+		`<tr><td>Green  tea</td><td>$3</td></tr><tr><td></td><td>$0</td></tr></table>"
+	}`
+
+	flattened := ParseCloudmailinWithOptions(input, ParseOptions{FlattenTables: true})
+	assert.Equal(t, "Your order\n\nItem | Price\nGreen tea | $3\n$0", flattened.Content)
+
+	def := ParseCloudmailinWithOptions(input, ParseOptions{})
+	assert.NotContains(t, def.Content, "Item | Price")
+}
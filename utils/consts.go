@@ -4,13 +4,38 @@ import pb "github.com/ziyixi/protos/go/todofy"
 
 // RecommendationModel is the preferred model for the recommendation
 // endpoint. This should mirror the strongest model available in
-// llm/consts.go llmModelPriority.
+// LLMModelPriority.
 var RecommendationModel = pb.Model_MODEL_GEMINI_3_FLASH_PREVIEW
 
+// LLMModelNames maps each model enum to the model string the provider API expects.
+// Shared by the LLM service and by the gateway when reporting which model answered.
+var LLMModelNames = map[pb.Model]string{
+	pb.Model_MODEL_GEMINI_2_5_PRO:         "gemini-2.5-pro",
+	pb.Model_MODEL_GEMINI_2_5_FLASH:       "gemini-2.5-flash",
+	pb.Model_MODEL_GEMINI_2_5_FLASH_LITE:  "gemini-2.5-flash-lite",
+	pb.Model_MODEL_GEMINI_3_FLASH_PREVIEW: "gemini-3-flash-preview",
+}
+
+// ModelName returns the provider model string for model, or "" for unknown models.
+func ModelName(model pb.Model) string {
+	return LLMModelNames[model]
+}
+
+// LLMModelPriority is the order the LLM service tries models in when a request
+// leaves the model unspecified; the first entry is the default.
+var LLMModelPriority = []pb.Model{
+	pb.Model_MODEL_GEMINI_2_5_FLASH_LITE,
+	pb.Model_MODEL_GEMINI_2_5_FLASH,
+	pb.Model_MODEL_GEMINI_3_FLASH_PREVIEW,
+}
+
+// SupportedModelFamilies lists the model families the LLM service accepts.
+var SupportedModelFamilies = []pb.ModelFamily{
+	pb.ModelFamily_MODEL_FAMILY_GEMINI,
+}
+
 // Key constants used throughout the application for context storage
 const (
-	// KeyGRPCClients is the context key for storing gRPC clients
-	KeyGRPCClients                 = "grpcClients"
 	SystemAutomaticallyEmailPrefix = "[Todofy System]"
 
 	DefaultPromptToSummaryEmail string = `Could you please provide a concise and comprehensive summary of the given ` +
@@ -78,4 +103,24 @@ Example output format (for 5 items):
 {"rank":5,"title":"任务标题","reason":"原因说明"}]
 
 The task summaries from the last 24 hours are as follows:`
+
+	// PrioritySenderMarker starts the header of recommendation entries sent by a --priority-senders address.
+	PrioritySenderMarker string = "[PRIORITY SENDER]"
+
+	// PromptPrioritySenders is appended to the recommendation prompt when priority senders are configured.
+	PromptPrioritySenders string = `IMPORTANT: Tasks whose header starts with ` + PrioritySenderMarker +
+		` come from people I always want to hear from. Rank them above otherwise comparable tasks.`
+
+	// PromptEntriesJSON is appended to the summary and recommendation prompts when entries are sent as JSON.
+	PromptEntriesJSON string = `IMPORTANT: The tasks are given as a JSON array. Each element has "index", ` +
+		`"date" (when it was received), "subject", "sender" and "summary" (the stored task body). ` +
+		`Elements with "priority_sender": true are the tasks marked ` + PrioritySenderMarker + `.`
+)
+
+// Response trailers the LLM service sets on Summarize; LLMSummaryResponse has no fields for them.
+const (
+	// LLMAttemptsTrailer holds the number of models Summarize tried.
+	LLMAttemptsTrailer = "x-llm-attempts"
+	// LLMFailedModelsTrailer holds the comma-separated names of the models that failed before the answer.
+	LLMFailedModelsTrailer = "x-llm-failed-models"
 )
@@ -4,14 +4,17 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+
+	pb "github.com/ziyixi/protos/go/todofy"
 )
 
-func TestConstants(t *testing.T) {
-	t.Run("KeyGRPCClients constant", func(t *testing.T) {
-		assert.Equal(t, "grpcClients", KeyGRPCClients)
-		assert.NotEmpty(t, KeyGRPCClients)
-	})
+func TestModelName(t *testing.T) {
+	assert.Equal(t, "gemini-2.5-flash-lite", ModelName(pb.Model_MODEL_GEMINI_2_5_FLASH_LITE))
+	assert.Equal(t, "gemini-3-flash-preview", ModelName(RecommendationModel))
+	assert.Empty(t, ModelName(pb.Model_MODEL_UNSPECIFIED))
+}
 
+func TestConstants(t *testing.T) {
 	t.Run("system prefix constant", func(t *testing.T) {
 		assert.Equal(t, "[Todofy System]", SystemAutomaticallyEmailPrefix)
 	})
@@ -5,6 +5,7 @@ import (
 	"log"
 	"net"
 
+	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/reflection"
@@ -15,6 +16,54 @@ import (
 // GRPCRegisterFunc is a type alias for the registration function
 type GRPCRegisterFunc[S any] func(grpc.ServiceRegistrar, S)
 
+type withoutReflectionOption struct {
+	grpc.EmptyServerOption
+}
+
+// WithReflection controls whether NewGRPCServer registers the reflection service.
+// Disabling it keeps the service schema from anyone who can reach the port.
+func WithReflection(enabled bool) grpc.ServerOption {
+	if enabled {
+		return grpc.EmptyServerOption{}
+	}
+	return withoutReflectionOption{}
+}
+
+// NewGRPCServer creates a gRPC server with per-RPC access logging, reflection
+// (unless disabled via WithReflection(false)) and a health service reporting SERVING.
+// The health server is returned so callers can publish per-backend statuses.
+func NewGRPCServer(opts ...grpc.ServerOption) (*grpc.Server, *health.Server) {
+	enableReflection := true
+	var rpcLogger logrus.FieldLogger = logrus.StandardLogger()
+	var authOpts []grpc.ServerOption
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case withoutReflectionOption:
+			enableReflection = false
+		case rpcLoggerOption:
+			rpcLogger = o.logger
+		case sharedSecretOption:
+			authOpts = sharedSecretServerOptions(o.secret)
+		}
+	}
+
+	// Logging goes first in the chain so rejected calls (e.g. auth failures) are logged too
+	serverOpts := append(
+		[]grpc.ServerOption{grpc.ChainUnaryInterceptor(RPCLoggingUnaryServerInterceptor(rpcLogger))},
+		opts...,
+	)
+	serverOpts = append(serverOpts, authOpts...)
+	srv := grpc.NewServer(serverOpts...)
+	if enableReflection {
+		reflection.Register(srv)
+	}
+
+	healthcheck := health.NewServer()
+	healthpb.RegisterHealthServer(srv, healthcheck)
+	healthcheck.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
+	return srv, healthcheck
+}
+
 // StartGRPCServer starts a gRPC server with the given service
 func StartGRPCServer[S any](
 	port int,
@@ -27,15 +76,10 @@ func StartGRPCServer[S any](
 		return fmt.Errorf("failed to listen: %v", err)
 	}
 
-	srv := grpc.NewServer(opts...)
+	srv, _ := NewGRPCServer(opts...)
 	registerFunc(srv, implementation)
-	reflection.Register(srv)
-
-	healthcheck := health.NewServer()
-	healthpb.RegisterHealthServer(srv, healthcheck)
 
 	log.Printf("Server is running on port %d", port)
-	healthcheck.SetServingStatus("", healthpb.HealthCheckResponse_SERVING)
 	if err := srv.Serve(lis); err != nil {
 		return fmt.Errorf("failed to serve: %v", err)
 	}
@@ -0,0 +1,112 @@
+package utils
+
+import (
+	"context"
+	"crypto/subtle"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const (
+	// authMetadataKey is the gRPC metadata key carrying the inter-service bearer token.
+	authMetadataKey = "authorization"
+	bearerPrefix    = "Bearer "
+)
+
+// healthMethodPrefix starts the full method name of every health service RPC.
+var healthMethodPrefix = "/" + healthpb.Health_ServiceDesc.ServiceName + "/"
+
+type sharedSecretOption struct {
+	grpc.EmptyServerOption
+	secret string
+}
+
+// SharedSecretServerOption makes NewGRPCServer reject unary and streaming calls without
+// the matching bearer token. An empty secret disables enforcement.
+func SharedSecretServerOption(secret string) grpc.ServerOption {
+	if secret == "" {
+		return grpc.EmptyServerOption{}
+	}
+	return sharedSecretOption{secret: secret}
+}
+
+// sharedSecretServerOptions installs the unary and stream shared-secret interceptors.
+func sharedSecretServerOptions(secret string) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(SharedSecretUnaryServerInterceptor(secret)),
+		grpc.ChainStreamInterceptor(SharedSecretStreamServerInterceptor(secret)),
+	}
+}
+
+// SharedSecretUnaryServerInterceptor validates the bearer token in incoming metadata.
+// Health checks are always allowed so container probes keep working without the secret.
+func SharedSecretUnaryServerInterceptor(secret string) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		if secret == "" || strings.HasPrefix(info.FullMethod, healthMethodPrefix) {
+			return handler(ctx, req)
+		}
+		if !hasBearerToken(ctx, secret) {
+			return nil, status.Error(codes.Unauthenticated, "missing or invalid inter-service auth token")
+		}
+		return handler(ctx, req)
+	}
+}
+
+// SharedSecretStreamServerInterceptor is SharedSecretUnaryServerInterceptor for streaming calls,
+// such as reflection. Health watches are allowed without the secret.
+func SharedSecretStreamServerInterceptor(secret string) grpc.StreamServerInterceptor {
+	return func(
+		srv any,
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if secret == "" || strings.HasPrefix(info.FullMethod, healthMethodPrefix) {
+			return handler(srv, stream)
+		}
+		if !hasBearerToken(stream.Context(), secret) {
+			return status.Error(codes.Unauthenticated, "missing or invalid inter-service auth token")
+		}
+		return handler(srv, stream)
+	}
+}
+
+// SharedSecretUnaryClientInterceptor attaches the bearer token to outgoing unary calls.
+func SharedSecretUnaryClientInterceptor(secret string) grpc.UnaryClientInterceptor {
+	return func(
+		ctx context.Context,
+		method string,
+		req, reply any,
+		cc *grpc.ClientConn,
+		invoker grpc.UnaryInvoker,
+		opts ...grpc.CallOption,
+	) error {
+		ctx = metadata.AppendToOutgoingContext(ctx, authMetadataKey, bearerPrefix+secret)
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func hasBearerToken(ctx context.Context, secret string) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	for _, value := range md.Get(authMetadataKey) {
+		token, found := strings.CutPrefix(value, bearerPrefix)
+		if found && subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "github.com/ziyixi/protos/go/todofy"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+)
+
+// startAuthTestServer serves an unimplemented LLM service plus health and reflection behind
+// the shared-secret interceptors and returns a client connection using clientToken.
+func startAuthTestServer(t *testing.T, serverSecret, clientToken string) *grpc.ClientConn {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	server, _ := NewGRPCServer(SharedSecretServerOption(serverSecret))
+	pb.RegisterLLMSummaryServiceServer(server, &pb.UnimplementedLLMSummaryServiceServer{})
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	opts := []grpc.DialOption{
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	}
+	if clientToken != "" {
+		opts = append(opts, grpc.WithChainUnaryInterceptor(SharedSecretUnaryClientInterceptor(clientToken)))
+	}
+	conn, err := grpc.NewClient("passthrough:///bufconn", opts...)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return conn
+}
+
+func summarizeCode(t *testing.T, conn *grpc.ClientConn) codes.Code {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err := pb.NewLLMSummaryServiceClient(conn).Summarize(ctx, &pb.LLMSummaryRequest{})
+	return status.Code(err)
+}
+
+// listServicesCode lists services over the reflection stream, attaching token when set.
+func listServicesCode(t *testing.T, conn *grpc.ClientConn, token string) codes.Code {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if token != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, authMetadataKey, bearerPrefix+token)
+	}
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	require.NoError(t, err)
+	err = stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	return status.Code(err)
+}
+
+func TestSharedSecretAuth_AcceptsMatchingToken(t *testing.T) {
+	conn := startAuthTestServer(t, "secret", "secret")
+	// Unimplemented means the call got past the interceptor to the service.
+	assert.Equal(t, codes.Unimplemented, summarizeCode(t, conn))
+}
+
+func TestSharedSecretAuth_RejectsMissingOrWrongToken(t *testing.T) {
+	assert.Equal(t, codes.Unauthenticated, summarizeCode(t, startAuthTestServer(t, "secret", "")))
+	assert.Equal(t, codes.Unauthenticated, summarizeCode(t, startAuthTestServer(t, "secret", "wrong")))
+}
+
+func TestSharedSecretAuth_RejectsStreamsWithoutToken(t *testing.T) {
+	conn := startAuthTestServer(t, "secret", "")
+	assert.Equal(t, codes.Unauthenticated, listServicesCode(t, conn, ""))
+	assert.Equal(t, codes.Unauthenticated, listServicesCode(t, conn, "wrong"))
+	assert.Equal(t, codes.OK, listServicesCode(t, conn, "secret"))
+}
+
+func TestSharedSecretAuth_EmptySecretSkipsEnforcement(t *testing.T) {
+	conn := startAuthTestServer(t, "", "")
+	assert.Equal(t, codes.Unimplemented, summarizeCode(t, conn))
+}
+
+func TestSharedSecretAuth_AllowsHealthChecksWithoutToken(t *testing.T) {
+	conn := startAuthTestServer(t, "secret", "")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	resp, err := grpc_health_v1.NewHealthClient(conn).Check(ctx, &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, resp.Status)
+
+	watch, err := grpc_health_v1.NewHealthClient(conn).Watch(ctx, &grpc_health_v1.HealthCheckRequest{})
+	require.NoError(t, err)
+	update, err := watch.Recv()
+	require.NoError(t, err, "health watches are streams and stay exempt too")
+	assert.Equal(t, grpc_health_v1.HealthCheckResponse_SERVING, update.Status)
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+type rpcLoggerOption struct {
+	grpc.EmptyServerOption
+	logger logrus.FieldLogger
+}
+
+// WithRPCLogger sets the logger NewGRPCServer uses for per-RPC access logs.
+// Without it the logrus standard logger is used.
+func WithRPCLogger(logger logrus.FieldLogger) grpc.ServerOption {
+	return rpcLoggerOption{logger: logger}
+}
+
+// RPCLoggingUnaryServerInterceptor logs method, status code and latency for every
+// unary call. Health checks are logged at debug level since probes run constantly.
+func RPCLoggingUnaryServerInterceptor(logger logrus.FieldLogger) grpc.UnaryServerInterceptor {
+	healthMethodPrefix := "/" + healthpb.Health_ServiceDesc.ServiceName + "/"
+	return func(
+		ctx context.Context,
+		req any,
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (any, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		code := status.Code(err)
+
+		entry := logger.WithFields(logrus.Fields{
+			"grpc.method":      info.FullMethod,
+			"grpc.code":        code.String(),
+			"grpc.duration_ms": time.Since(start).Milliseconds(),
+		})
+		switch {
+		case strings.HasPrefix(info.FullMethod, healthMethodPrefix):
+			entry.Debug("gRPC call finished")
+		case err != nil:
+			entry.WithError(err).Warn("gRPC call failed")
+		default:
+			entry.Info("gRPC call finished")
+		}
+		return resp, err
+	}
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	pb "github.com/ziyixi/protos/go/todofy"
+)
+
+func TestRPCLoggingUnaryServerInterceptor_RecordsCall(t *testing.T) {
+	logger, hook := logtest.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+
+	listener := bufconn.Listen(1024 * 1024)
+	server, _ := NewGRPCServer(WithRPCLogger(logger))
+	pb.RegisterLLMSummaryServiceServer(server, &pb.UnimplementedLLMSummaryServiceServer{})
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	_, err = pb.NewLLMSummaryServiceClient(conn).Summarize(ctx, &pb.LLMSummaryRequest{})
+	require.Equal(t, codes.Unimplemented, status.Code(err))
+
+	entry := hook.LastEntry()
+	require.NotNil(t, entry)
+	assert.Equal(t, logrus.WarnLevel, entry.Level)
+	assert.Equal(t, "/todofy.LLMSummaryService/Summarize", entry.Data["grpc.method"])
+	assert.Equal(t, codes.Unimplemented.String(), entry.Data["grpc.code"])
+	assert.Contains(t, entry.Data, "grpc.duration_ms")
+}
+
+func TestRPCLoggingUnaryServerInterceptor_LevelByOutcome(t *testing.T) {
+	logger, hook := logtest.NewNullLogger()
+	logger.SetLevel(logrus.DebugLevel)
+	interceptor := RPCLoggingUnaryServerInterceptor(logger)
+	ok := func(context.Context, any) (any, error) { return "ok", nil }
+
+	resp, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/svc/Method"}, ok)
+	require.NoError(t, err)
+	assert.Equal(t, "ok", resp)
+	assert.Equal(t, logrus.InfoLevel, hook.LastEntry().Level)
+	assert.Equal(t, codes.OK.String(), hook.LastEntry().Data["grpc.code"])
+
+	healthInfo := &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}
+	_, err = interceptor(context.Background(), nil, healthInfo, ok)
+	require.NoError(t, err)
+	assert.Equal(t, logrus.DebugLevel, hook.LastEntry().Level)
+}
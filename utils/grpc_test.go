@@ -9,10 +9,14 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
 	"google.golang.org/grpc/test/bufconn"
+
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
 )
 
 // mockService implements a simple gRPC service for testing
@@ -96,6 +100,52 @@ func TestStartGRPCServer(t *testing.T) {
 	})
 }
 
+func reflectionListServices(t *testing.T, opts ...grpc.ServerOption) error {
+	t.Helper()
+
+	listener := bufconn.Listen(1024 * 1024)
+	server, _ := NewGRPCServer(opts...)
+	go func() {
+		_ = server.Serve(listener)
+	}()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) {
+			return listener.Dial()
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(ctx)
+	require.NoError(t, err)
+	err = stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	})
+	require.NoError(t, err)
+	_, err = stream.Recv()
+	return err
+}
+
+func TestNewGRPCServer_Reflection(t *testing.T) {
+	t.Run("registered by default", func(t *testing.T) {
+		assert.NoError(t, reflectionListServices(t))
+		assert.NoError(t, reflectionListServices(t, WithReflection(true)))
+	})
+
+	t.Run("not registered when disabled", func(t *testing.T) {
+		err := reflectionListServices(t, WithReflection(false))
+		assert.Equal(t, codes.Unimplemented, status.Code(err))
+	})
+}
+
 func TestGRPCRegisterFunc(t *testing.T) {
 	// Test that the type alias works correctly
 	registerFunc := func(srv grpc.ServiceRegistrar, impl *mockService) {
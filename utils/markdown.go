@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	mdCodeFence      = regexp.MustCompile("(?m)^[ \t]*```.*$\n?")
+	mdHorizontalRule = regexp.MustCompile(`(?m)^[ \t]*(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$\n?`)
+	mdHeading        = regexp.MustCompile(`(?m)^[ \t]{0,3}#{1,6}[ \t]+(.*?)[ \t]*#*[ \t]*$`)
+	mdBlockquote     = regexp.MustCompile(`(?m)^[ \t]{0,3}>[ \t]?`)
+	mdBullet         = regexp.MustCompile(`(?m)^([ \t]*)[*+][ \t]+`)
+	mdImage          = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	mdLink           = regexp.MustCompile(`\[([^\]]+)\]\(\s*([^)\s]*)[^)]*\)`)
+	mdBold           = regexp.MustCompile(`(\*\*|__)(\S(?:.*?\S)?)(\*\*|__)`)
+	mdItalicStar     = regexp.MustCompile(`\*(\S(?:[^*]*?\S)?)\*`)
+	mdItalicUnder    = regexp.MustCompile(`(^|\W)_(\S(?:[^_]*?\S)?)_(\W|$)`)
+	mdInlineCode     = regexp.MustCompile("`([^`]+)`")
+	mdBlankLines     = regexp.MustCompile(`\n{3,}`)
+)
+
+// MarkdownToPlainText converts markdown to plain text for delivery channels that
+// render markdown poorly. Formatting markers are dropped, links become
+// "text (url)", bullets become "- ", and runs of blank lines are collapsed.
+func MarkdownToPlainText(markdown string) string {
+	text := strings.ReplaceAll(markdown, "\r\n", "\n")
+	text = mdCodeFence.ReplaceAllString(text, "")
+	text = mdHorizontalRule.ReplaceAllString(text, "")
+	text = mdHeading.ReplaceAllString(text, "$1")
+	text = mdBlockquote.ReplaceAllString(text, "")
+	text = mdBullet.ReplaceAllString(text, "$1- ")
+	text = mdImage.ReplaceAllString(text, "$1")
+	text = mdLink.ReplaceAllStringFunc(text, func(link string) string {
+		match := mdLink.FindStringSubmatch(link)
+		if match[2] == "" || match[2] == match[1] {
+			return match[1]
+		}
+		return match[1] + " (" + match[2] + ")"
+	})
+	text = mdBold.ReplaceAllString(text, "$2")
+	text = mdItalicStar.ReplaceAllString(text, "$1")
+	text = mdItalicUnder.ReplaceAllString(text, "$1$2$3")
+	text = mdInlineCode.ReplaceAllString(text, "$1")
+	text = mdBlankLines.ReplaceAllString(text, "\n\n")
+	return strings.TrimSpace(text)
+}
@@ -0,0 +1,37 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarkdownToPlainText(t *testing.T) {
+	tests := []struct {
+		name     string
+		markdown string
+		want     string
+	}{
+		{name: "headings", markdown: "## Action items ##\n### Details", want: "Action items\nDetails"},
+		{name: "emphasis", markdown: "**Due** by *Friday* and __not__ _later_", want: "Due by Friday and not later"},
+		{name: "keeps snake_case", markdown: "rename user_id to account_id", want: "rename user_id to account_id"},
+		{name: "bullets", markdown: "* one\n+ two\n  * nested\n- three", want: "- one\n- two\n  - nested\n- three"},
+		{name: "numbered list untouched", markdown: "1. first\n2. second", want: "1. first\n2. second"},
+		{
+			name:     "links",
+			markdown: "See [the doc](https://example.com/d) and [empty]()",
+			want:     "See the doc (https://example.com/d) and empty",
+		},
+		{name: "images", markdown: "![chart](https://example.com/c.png)", want: "chart"},
+		{name: "inline code and fences", markdown: "Run `make`:\n```sh\nmake test\n```", want: "Run make:\nmake test"},
+		{name: "blockquote and rule", markdown: "> quoted\n\n---\n\nafter", want: "quoted\n\nafter"},
+		{name: "collapses blank lines", markdown: "a\r\n\r\n\r\n\r\nb", want: "a\n\nb"},
+		{name: "plain text unchanged", markdown: "Nothing to convert.", want: "Nothing to convert."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, MarkdownToPlainText(tt.markdown))
+		})
+	}
+}
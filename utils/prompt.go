@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// recommendTopTasksVerbCount is the number of %d verbs the recommendation prompt
+// must contain; each one is filled with the requested top-N.
+const recommendTopTasksVerbCount = 4
+
+// ValidateRecommendTopTasksPrompt checks that prompt has exactly the %d verbs
+// FormatRecommendTopTasksPrompt fills, so edits don't silently produce %!d(MISSING).
+func ValidateRecommendTopTasksPrompt(prompt string) error {
+	verbs := formatVerbs(prompt)
+	if len(verbs) != recommendTopTasksVerbCount {
+		return fmt.Errorf("recommendation prompt must contain exactly %d %%d verbs, found %d verbs",
+			recommendTopTasksVerbCount, len(verbs))
+	}
+	for _, verb := range verbs {
+		if verb != 'd' {
+			return fmt.Errorf("recommendation prompt contains unsupported verb %%%c; only %%d is allowed", verb)
+		}
+	}
+	return nil
+}
+
+// FormatRecommendTopTasksPrompt substitutes topN into every %d verb of prompt.
+func FormatRecommendTopTasksPrompt(prompt string, topN int) (string, error) {
+	if err := ValidateRecommendTopTasksPrompt(prompt); err != nil {
+		return "", err
+	}
+	args := make([]any, recommendTopTasksVerbCount)
+	for i := range args {
+		args[i] = topN
+	}
+	return fmt.Sprintf(prompt, args...), nil
+}
+
+// formatVerbs returns the verb letter of every fmt directive in format, ignoring %%.
+func formatVerbs(format string) []rune {
+	var verbs []rune
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		// Skip flags, width and precision to reach the verb
+		j := i + 1
+		for j < len(format) && strings.IndexByte("+-# 0123456789.*[]", format[j]) >= 0 {
+			j++
+		}
+		if j >= len(format) {
+			verbs = append(verbs, '!')
+			break
+		}
+		if format[j] != '%' {
+			verbs = append(verbs, rune(format[j]))
+		}
+		i = j
+	}
+	return verbs
+}
+
+// DefaultSummaryCategories are the groups DefaultPromptToSummaryEmailRange sorts emails into.
+// The last category collects everything the model considers unimportant.
+var DefaultSummaryCategories = []string{"Important", "Urgent", "Normal", "Low Priority"}
+
+var countWords = []string{"zero", "one", "two", "three", "four", "five", "six", "seven", "eight", "nine", "ten"}
+
+// summaryCategoriesInstruction renders the prompt line listing categories, matching the
+// wording DefaultPromptToSummaryEmailRange uses for DefaultSummaryCategories.
+func summaryCategoriesInstruction(categories []string) string {
+	count := strconv.Itoa(len(categories))
+	if len(categories) < len(countWords) {
+		count = countWords[len(categories)]
+	}
+	quoted := make([]string, len(categories))
+	for i, category := range categories {
+		quoted[i] = strconv.Quote(category)
+	}
+	return fmt.Sprintf(`IMPORTANT: Please group emails into %s categories: %s. `+
+		`If you think the email is not important, please put it into %s category.`,
+		count, strings.Join(quoted, ", "), quoted[len(quoted)-1])
+}
+
+// FormatSummaryRangePrompt returns DefaultPromptToSummaryEmailRange grouping emails into
+// categories instead of DefaultSummaryCategories; no categories keeps the default prompt.
+func FormatSummaryRangePrompt(categories []string) string {
+	if len(categories) == 0 {
+		return DefaultPromptToSummaryEmailRange
+	}
+	return strings.Replace(DefaultPromptToSummaryEmailRange,
+		summaryCategoriesInstruction(DefaultSummaryCategories), summaryCategoriesInstruction(categories), 1)
+}
+
+// WrapPrompt brackets prompt with prefix and suffix, each separated from it by a blank line.
+// Empty parts are skipped, so WrapPrompt(prompt, "", "") returns prompt unchanged.
+func WrapPrompt(prompt, prefix, suffix string) string {
+	parts := make([]string, 0, 3)
+	for _, part := range []string{prefix, prompt, suffix} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// ParseSummaryCategories parses a comma-separated category list such as "Urgent,Waiting,Low Priority".
+// Empty entries are dropped; duplicate names and names containing double quotes are rejected.
+func ParseSummaryCategories(value string) ([]string, error) {
+	var categories []string
+	for _, part := range strings.Split(value, ",") {
+		category := strings.TrimSpace(part)
+		if category == "" {
+			continue
+		}
+		if strings.Contains(category, `"`) {
+			return nil, fmt.Errorf("category %s must not contain double quotes", category)
+		}
+		if slices.ContainsFunc(categories, func(c string) bool { return strings.EqualFold(c, category) }) {
+			return nil, fmt.Errorf("duplicate category %q", category)
+		}
+		categories = append(categories, category)
+	}
+	return categories, nil
+}
@@ -0,0 +1,79 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRecommendTopTasksPrompt_Default(t *testing.T) {
+	require.NoError(t, ValidateRecommendTopTasksPrompt(DefaultPromptToRecommendTopTasks))
+}
+
+func TestValidateRecommendTopTasksPrompt_Mismatched(t *testing.T) {
+	tests := map[string]string{
+		"too few verbs":    "pick %d tasks, rank 1-%d",
+		"too many verbs":   "%d %d %d %d %d",
+		"non-integer verb": "%d %d %d %s",
+		"trailing percent": "%d %d %d %d %",
+	}
+	for name, prompt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Error(t, ValidateRecommendTopTasksPrompt(prompt))
+		})
+	}
+}
+
+func TestFormatRecommendTopTasksPrompt(t *testing.T) {
+	prompt, err := FormatRecommendTopTasksPrompt("top %d, #%d, 1-%d, exactly %d, 100%% sure", 5)
+	require.NoError(t, err)
+	assert.Equal(t, "top 5, #5, 1-5, exactly 5, 100% sure", prompt)
+
+	_, err = FormatRecommendTopTasksPrompt("only %d", 5)
+	assert.Error(t, err)
+}
+
+func TestFormatSummaryRangePrompt(t *testing.T) {
+	t.Run("default categories keep the default prompt", func(t *testing.T) {
+		assert.Equal(t, DefaultPromptToSummaryEmailRange, FormatSummaryRangePrompt(nil))
+		assert.Equal(t, DefaultPromptToSummaryEmailRange, FormatSummaryRangePrompt(DefaultSummaryCategories))
+	})
+
+	t.Run("custom categories replace the category instruction", func(t *testing.T) {
+		prompt := FormatSummaryRangePrompt([]string{"Urgent", "Waiting", "Later"})
+		assert.Contains(t, prompt, `group emails into three categories: "Urgent", "Waiting", "Later". `+
+			`If you think the email is not important, please put it into "Later" category.`)
+		assert.NotContains(t, prompt, "Low Priority")
+		assert.Contains(t, prompt, "Similar emails should be treated as one email.")
+	})
+
+	t.Run("large category counts use digits", func(t *testing.T) {
+		categories := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j", "k"}
+		assert.Contains(t, FormatSummaryRangePrompt(categories), "into 11 categories")
+	})
+}
+
+func TestWrapPrompt(t *testing.T) {
+	assert.Equal(t, "base", WrapPrompt("base", "", ""))
+	assert.Equal(t, "Be brief.\n\nbase", WrapPrompt("base", "Be brief.", ""))
+	assert.Equal(t, "base\n\nAlways mention deadlines.", WrapPrompt("base", "", "Always mention deadlines."))
+	assert.Equal(t, "Be brief.\n\nbase\n\nAlways mention deadlines.",
+		WrapPrompt("base", "Be brief.", "Always mention deadlines."))
+}
+
+func TestParseSummaryCategories(t *testing.T) {
+	categories, err := ParseSummaryCategories(" Urgent , ,Waiting,Low Priority ")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Urgent", "Waiting", "Low Priority"}, categories)
+
+	categories, err = ParseSummaryCategories("")
+	require.NoError(t, err)
+	assert.Empty(t, categories)
+
+	_, err = ParseSummaryCategories("Urgent,urgent")
+	assert.ErrorContains(t, err, "duplicate category")
+
+	_, err = ParseSummaryCategories(`Say "hi"`)
+	assert.ErrorContains(t, err, "double quotes")
+}
@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// ResolveSecret returns inline when set, otherwise the contents of path with
+// trailing newlines trimmed, so secrets can be mounted as files. Both empty yields "".
+func ResolveSecret(inline, path string) (string, error) {
+	if inline != "" || path == "" {
+		return inline, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file: %w", err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
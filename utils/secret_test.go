@@ -0,0 +1,38 @@
+package utils
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveSecret(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api-key")
+	require.NoError(t, os.WriteFile(path, []byte("from-file\r\n\n"), 0o600))
+
+	t.Run("reads and trims the file", func(t *testing.T) {
+		secret, err := ResolveSecret("", path)
+		require.NoError(t, err)
+		assert.Equal(t, "from-file", secret)
+	})
+
+	t.Run("inline value takes precedence", func(t *testing.T) {
+		secret, err := ResolveSecret("inline", path)
+		require.NoError(t, err)
+		assert.Equal(t, "inline", secret)
+	})
+
+	t.Run("neither set", func(t *testing.T) {
+		secret, err := ResolveSecret("", "")
+		require.NoError(t, err)
+		assert.Empty(t, secret)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := ResolveSecret("", filepath.Join(t.TempDir(), "missing"))
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,39 @@
+package utils
+
+import "context"
+
+// Semaphore bounds the number of concurrent holders of a shared resource.
+type Semaphore struct {
+	slots chan struct{}
+}
+
+// NewSemaphore creates a semaphore that admits up to limit concurrent holders.
+// A limit of 0 or less disables the bound.
+func NewSemaphore(limit int) *Semaphore {
+	if limit <= 0 {
+		return &Semaphore{}
+	}
+	return &Semaphore{slots: make(chan struct{}, limit)}
+}
+
+// Acquire blocks until a slot is free or ctx is done.
+func (s *Semaphore) Acquire(ctx context.Context) error {
+	if s == nil || s.slots == nil {
+		return nil
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot previously taken with Acquire.
+func (s *Semaphore) Release() {
+	if s == nil || s.slots == nil {
+		return
+	}
+	<-s.slots
+}
@@ -0,0 +1,36 @@
+package utils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSemaphore_BlocksBeyondLimit(t *testing.T) {
+	sem := NewSemaphore(1)
+	require.NoError(t, sem.Acquire(context.Background()))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := sem.Acquire(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	sem.Release()
+	require.NoError(t, sem.Acquire(context.Background()))
+	sem.Release()
+}
+
+func TestSemaphore_ZeroLimitIsUnbounded(t *testing.T) {
+	sem := NewSemaphore(0)
+	for i := 0; i < 100; i++ {
+		require.NoError(t, sem.Acquire(context.Background()))
+	}
+	sem.Release()
+
+	var nilSem *Semaphore
+	assert.NoError(t, nilSem.Acquire(context.Background()))
+	nilSem.Release()
+}
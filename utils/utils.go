@@ -72,9 +72,10 @@ func RateLimitMiddleware() gin.HandlerFunc {
 func RateLimitMiddlewareWithLimit(limit int) gin.HandlerFunc {
 	limiter := NewSlidingWindowLimiter(limit, time.Minute)
 	return func(c *gin.Context) {
-		allowed, _ := limiter.Reserve(time.Now())
+		allowed, retryAfter := limiter.Reserve(time.Now())
 		if !allowed {
-			c.JSON(http.StatusServiceUnavailable, gin.H{
+			c.Header("Retry-After", retryAfterSeconds(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{
 				"error": rateLimitErrorMessage,
 			})
 			c.Abort()
@@ -84,6 +85,16 @@ func RateLimitMiddlewareWithLimit(limit int) gin.HandlerFunc {
 	}
 }
 
+// retryAfterSeconds formats a wait as whole Retry-After seconds, rounding up so
+// clients never retry before the window has room again.
+func retryAfterSeconds(wait time.Duration) string {
+	seconds := int64((wait + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	return strconv.FormatInt(seconds, 10)
+}
+
 func rateLimitRequestsPerMinuteFromEnv() int {
 	raw, ok := os.LookupEnv(rateLimitRequestsPerMinuteEnv)
 	if !ok || strings.TrimSpace(raw) == "" {
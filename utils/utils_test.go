@@ -4,7 +4,9 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -167,13 +169,24 @@ func TestRateLimitMiddleware(t *testing.T) {
 		w := httptest.NewRecorder()
 		req, _ := http.NewRequest("GET", "/test", nil)
 		router.ServeHTTP(w, req)
-		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+		require.NoError(t, err)
+		assert.Greater(t, retryAfter, 0)
+		assert.LessOrEqual(t, retryAfter, 60)
 
 		var response map[string]interface{}
 		_ = json.NewDecoder(w.Body).Decode(&response) // Best effort decode
 		assert.Contains(t, response["error"], "Too many requests")
 	})
 
+	t.Run("retry after rounds up to whole seconds", func(t *testing.T) {
+		assert.Equal(t, "1", retryAfterSeconds(0))
+		assert.Equal(t, "1", retryAfterSeconds(200*time.Millisecond))
+		assert.Equal(t, "2", retryAfterSeconds(1500*time.Millisecond))
+		assert.Equal(t, "60", retryAfterSeconds(time.Minute))
+	})
+
 	t.Run("can be disabled with zero limit", func(t *testing.T) {
 		router := gin.New()
 		router.Use(RateLimitMiddlewareWithLimit(0))
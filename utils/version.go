@@ -0,0 +1,21 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// PrintVersion writes build metadata for a binary's --version output.
+// Values not injected via -ldflags are reported as "unknown".
+func PrintVersion(w io.Writer, binary, gitCommit, buildDate string) {
+	_, _ = fmt.Fprintf(w, "%s\n  git commit: %s\n  go version: %s\n  build date: %s\n",
+		binary, orUnknown(gitCommit), runtime.Version(), orUnknown(buildDate))
+}
+
+func orUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
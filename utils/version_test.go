@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"bytes"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrintVersion(t *testing.T) {
+	var buf bytes.Buffer
+	PrintVersion(&buf, "todofy-llm", "abc123", "2026-01-02T03:04:05Z")
+
+	out := buf.String()
+	assert.Contains(t, out, "todofy-llm")
+	assert.Contains(t, out, "git commit: abc123")
+	assert.Contains(t, out, "go version: "+runtime.Version())
+	assert.Contains(t, out, "build date: 2026-01-02T03:04:05Z")
+}
+
+func TestPrintVersion_UnknownWhenUnset(t *testing.T) {
+	var buf bytes.Buffer
+	PrintVersion(&buf, "todofy", "", "")
+
+	assert.Contains(t, buf.String(), "git commit: unknown")
+	assert.Contains(t, buf.String(), "build date: unknown")
+}